@@ -3,15 +3,17 @@ module github.com/DevNewbie1826/http-over-netpoll
 go 1.25.4
 
 require (
+	github.com/bytedance/gopkg v0.1.1
 	github.com/cloudwego/hertz v0.10.3
 	github.com/cloudwego/netpoll v0.7.2
 	github.com/lxzan/gws v1.8.9
 	github.com/valyala/fasthttp v1.68.0
+	golang.org/x/net v0.46.0
+	golang.org/x/sys v0.37.0
 )
 
 require (
 	github.com/andybalholm/brotli v1.2.0 // indirect
-	github.com/bytedance/gopkg v0.1.1 // indirect
 	github.com/bytedance/sonic v1.14.0 // indirect
 	github.com/bytedance/sonic/loader v0.3.0 // indirect
 	github.com/cloudwego/base64x v0.1.6 // indirect
@@ -30,6 +32,6 @@ require (
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	golang.org/x/arch v0.20.0 // indirect
-	golang.org/x/sys v0.37.0 // indirect
+	golang.org/x/text v0.30.0 // indirect
 	google.golang.org/protobuf v1.36.9 // indirect
 )
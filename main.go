@@ -23,6 +23,9 @@ func sseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 스트림은 엔진의 요청 타임아웃 없이 클라이언트가 끊을 때까지 유지합니다.
+	engine.SetRequestTimeout(r, 0)
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
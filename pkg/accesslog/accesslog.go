@@ -0,0 +1,342 @@
+// Package accesslog writes one line per HTTP request in the Apache Common or Combined log format,
+// or as JSON. Lines are formatted into pooled buffers and written to the destination by a background
+// goroutine, so a slow writer never holds up a request.
+// accesslog 패키지는 HTTP 요청마다 Apache Common 또는 Combined 로그 형식, 혹은 JSON으로 한 줄을 씁니다.
+// 줄은 풀링된 버퍼에 포맷되고 백그라운드 고루틴이 대상에 쓰므로, 느린 Writer가 요청을 붙잡지 않습니다.
+package accesslog
+
+import (
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/bytebufferpool"
+)
+
+// Format selects the layout of a log line. // Format은 로그 줄의 형태를 선택합니다.
+type Format int
+
+const (
+	// Common is the Apache Common Log Format: host ident user [time] "request" status bytes.
+	// Common은 Apache Common Log Format입니다: host ident user [time] "request" status bytes.
+	Common Format = iota
+	// Combined is Common followed by the quoted Referer and User-Agent.
+	// Combined는 Common 뒤에 따옴표로 감싼 Referer와 User-Agent가 붙습니다.
+	Combined
+	// JSON writes one object per line with the fields of Combined plus the protocol and duration.
+	// JSON은 Combined의 필드에 프로토콜과 실행 시간을 더한 객체를 줄마다 하나씩 씁니다.
+	JSON
+)
+
+const (
+	defaultBufferSize = 1024
+	// maxBatch caps how much the writer goroutine gathers before one Write call.
+	// maxBatch는 Writer 고루틴이 한 번의 Write 호출 전에 모으는 양을 제한합니다.
+	maxBatch = 64 << 10
+)
+
+// SkipFunc reports whether a finished request should be left out of the log.
+// SkipFunc는 완료된 요청을 로그에서 뺄지 보고합니다.
+type SkipFunc func(r *http.Request, status int) bool
+
+// Option configures a Logger. // Option은 Logger를 설정합니다.
+type Option func(*Logger)
+
+// WithFormat sets the line format. The default is Combined.
+// WithFormat은 줄 형식을 설정합니다. 기본값은 Combined입니다.
+func WithFormat(f Format) Option {
+	return func(l *Logger) {
+		l.format = f
+	}
+}
+
+// WithSampling logs only the given fraction, between 0 and 1, of successful requests.
+// Requests answered with a status of 400 or above are always logged.
+// WithSampling은 성공한 요청 중 주어진 비율(0과 1 사이)만 기록합니다.
+// 400 이상의 상태로 응답한 요청은 항상 기록합니다.
+func WithSampling(rate float64) Option {
+	return func(l *Logger) {
+		l.sampling = min(max(rate, 0), 1)
+	}
+}
+
+// WithSkip leaves out requests for which any of fns reports true; it may be given more than once.
+// WithSkip은 fns 중 하나라도 true를 보고하는 요청을 제외하며, 여러 번 지정할 수 있습니다.
+func WithSkip(fns ...SkipFunc) Option {
+	return func(l *Logger) {
+		l.skip = append(l.skip, fns...)
+	}
+}
+
+// WithBufferSize sets how many lines may wait for the writer; further lines are dropped and counted
+// by Dropped. The default is 1024.
+// WithBufferSize는 Writer를 기다릴 수 있는 줄 수를 설정합니다. 그 이상의 줄은 버려지고 Dropped로 집계됩니다.
+// 기본값은 1024입니다.
+func WithBufferSize(n int) Option {
+	return func(l *Logger) {
+		l.bufferSize = n
+	}
+}
+
+// SkipPaths returns a SkipFunc for requests whose URL path is one of paths, such as health checks.
+// SkipPaths는 헬스 체크처럼 URL 경로가 paths 중 하나인 요청을 위한 SkipFunc를 반환합니다.
+func SkipPaths(paths ...string) SkipFunc {
+	set := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		set[p] = struct{}{}
+	}
+	return func(r *http.Request, _ int) bool {
+		_, ok := set[r.URL.Path]
+		return ok
+	}
+}
+
+// Logger formats access log lines and writes them asynchronously. It is safe for concurrent use.
+// Logger는 액세스 로그 줄을 포맷하고 비동기로 씁니다. 동시에 사용해도 안전합니다.
+type Logger struct {
+	w          io.Writer
+	format     Format
+	sampling   float64
+	skip       []SkipFunc
+	bufferSize int
+
+	mu      sync.RWMutex // Guards closing lines against sends. // 줄 전송과 채널 닫기를 보호합니다.
+	closed  bool
+	lines   chan *bytebufferpool.ByteBuffer
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// New creates a Logger writing to w and starts its writer goroutine; call Close to stop it.
+// New는 w에 쓰는 Logger를 생성하고 Writer 고루틴을 시작합니다. 멈추려면 Close를 호출합니다.
+func New(w io.Writer, opts ...Option) *Logger {
+	l := &Logger{
+		w:          w,
+		format:     Combined,
+		sampling:   1,
+		bufferSize: defaultBufferSize,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.lines = make(chan *bytebufferpool.ByteBuffer, max(l.bufferSize, 1))
+	l.done = make(chan struct{})
+	go l.run()
+	return l
+}
+
+// Log records a finished request: the status sent, the response bytes written to the connection,
+// when the request started and how long it took. Lines that cannot be queued are dropped.
+// Log는 완료된 요청을 기록합니다: 보낸 상태, 연결에 쓴 응답 바이트, 요청이 시작된 시각과 걸린 시간.
+// 큐에 넣을 수 없는 줄은 버려집니다.
+func (l *Logger) Log(r *http.Request, status int, bytes int64, start time.Time, d time.Duration) {
+	if status < 400 && l.sampling < 1 && rand.Float64() >= l.sampling {
+		return
+	}
+	for _, skip := range l.skip {
+		if skip(r, status) {
+			return
+		}
+	}
+
+	buf := bytebufferpool.Get()
+	if l.format == JSON {
+		buf.B = appendJSON(buf.B, r, status, bytes, start, d)
+	} else {
+		buf.B = appendCommon(buf.B, r, status, bytes, start)
+		if l.format == Combined {
+			buf.B = append(buf.B, ' ')
+			buf.B = appendQuoted(buf.B, r.Referer())
+			buf.B = append(buf.B, ' ')
+			buf.B = appendQuoted(buf.B, r.UserAgent())
+		}
+	}
+	buf.B = append(buf.B, '\n')
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		bytebufferpool.Put(buf)
+		return
+	}
+	select {
+	case l.lines <- buf:
+	default:
+		bytebufferpool.Put(buf)
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of lines discarded because the writer could not keep up.
+// Dropped는 Writer가 따라가지 못해 버려진 줄 수를 반환합니다.
+func (l *Logger) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// Close writes the queued lines and stops the writer goroutine. Later calls to Log are ignored.
+// Close는 큐에 있는 줄을 쓰고 Writer 고루틴을 멈춥니다. 이후의 Log 호출은 무시됩니다.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.lines)
+	}
+	l.mu.Unlock()
+	<-l.done
+	return nil
+}
+
+// run gathers queued lines into batches and writes them until the queue is closed.
+// run은 큐의 줄을 배치로 모아 큐가 닫힐 때까지 씁니다.
+func (l *Logger) run() {
+	defer close(l.done)
+	batch := bytebufferpool.Get()
+	defer bytebufferpool.Put(batch)
+	for line := range l.lines {
+		batch.B = append(batch.B[:0], line.B...)
+		bytebufferpool.Put(line)
+	gather:
+		for len(batch.B) < maxBatch {
+			select {
+			case line, ok := <-l.lines:
+				if !ok {
+					break gather
+				}
+				batch.B = append(batch.B, line.B...)
+				bytebufferpool.Put(line)
+			default:
+				break gather
+			}
+		}
+		// A failing destination cannot be reported anywhere useful, so its lines are lost.
+		// 실패하는 대상은 유용한 곳에 보고할 수 없으므로 그 줄들은 유실됩니다.
+		_, _ = l.w.Write(batch.B)
+	}
+}
+
+// appendCommon appends the Common Log Format fields. // appendCommon은 Common Log Format 필드를 덧붙입니다.
+func appendCommon(b []byte, r *http.Request, status int, bytes int64, start time.Time) []byte {
+	b = append(b, remoteHost(r)...)
+	b = append(b, " - "...)
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		b = appendEscaped(b, user)
+	} else {
+		b = append(b, '-')
+	}
+	b = append(b, " ["...)
+	b = start.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+	b = append(b, "] "...)
+	b = appendQuoted(b, r.Method+" "+r.RequestURI+" "+r.Proto)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(status), 10)
+	b = append(b, ' ')
+	if bytes > 0 {
+		b = strconv.AppendInt(b, bytes, 10)
+	} else {
+		b = append(b, '-')
+	}
+	return b
+}
+
+// appendJSON appends the line as a JSON object. // appendJSON은 줄을 JSON 객체로 덧붙입니다.
+func appendJSON(b []byte, r *http.Request, status int, bytes int64, start time.Time, d time.Duration) []byte {
+	b = append(b, `{"time":"`...)
+	b = start.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, `","remote_addr":`...)
+	b = appendJSONString(b, r.RemoteAddr)
+	b = append(b, `,"method":`...)
+	b = appendJSONString(b, r.Method)
+	b = append(b, `,"uri":`...)
+	b = appendJSONString(b, r.RequestURI)
+	b = append(b, `,"proto":`...)
+	b = appendJSONString(b, r.Proto)
+	b = append(b, `,"status":`...)
+	b = strconv.AppendInt(b, int64(status), 10)
+	b = append(b, `,"bytes":`...)
+	b = strconv.AppendInt(b, bytes, 10)
+	b = append(b, `,"duration_ms":`...)
+	b = strconv.AppendFloat(b, float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	b = append(b, `,"referer":`...)
+	b = appendJSONString(b, r.Referer())
+	b = append(b, `,"user_agent":`...)
+	b = appendJSONString(b, r.UserAgent())
+	return append(b, '}')
+}
+
+// remoteHost returns the remote address without its port. // remoteHost는 포트를 뺀 원격 주소를 반환합니다.
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	if r.RemoteAddr == "" {
+		return "-"
+	}
+	return r.RemoteAddr
+}
+
+// appendQuoted appends s in double quotes, escaped as Apache does, or "-" when s is empty.
+// appendQuoted는 s를 Apache처럼 이스케이프하여 큰따옴표로 감싸 덧붙이며, s가 비었으면 "-"를 덧붙입니다.
+func appendQuoted(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, `"-"`...)
+	}
+	b = append(b, '"')
+	b = appendEscaped(b, s)
+	return append(b, '"')
+}
+
+// appendEscaped appends s with quotes, backslashes and non-printable bytes escaped, so a client cannot
+// forge log lines.
+// appendEscaped는 클라이언트가 로그 줄을 위조할 수 없도록 따옴표, 역슬래시, 출력할 수 없는 바이트를
+// 이스케이프하여 s를 덧붙입니다.
+func appendEscaped(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20 || c >= 0x7f:
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// appendJSONString appends s as a JSON string, replacing invalid UTF-8 with U+FFFD.
+// appendJSONString은 s를 JSON 문자열로 덧붙이며, 잘못된 UTF-8은 U+FFFD로 바꿉니다.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c < 0x20:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, "\ufffd"...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,137 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer collects the lines written by the Logger's goroutine.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+var start = time.Date(2024, time.March, 5, 14, 7, 9, 0, time.FixedZone("", 9*60*60))
+
+func request() *http.Request {
+	r := httptest.NewRequest("GET", "/search?q=\"x\"", nil)
+	r.RemoteAddr = "203.0.113.7:4321"
+	r.Header.Set("User-Agent", "curl/8.0")
+	r.Header.Set("Referer", "http://example.com/")
+	r.SetBasicAuth("alice", "secret")
+	return r
+}
+
+func TestFormats(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format Format
+		want   string
+	}{
+		{"common", Common, `203.0.113.7 - alice [05/Mar/2024:14:07:09 +0900] "GET /search?q=\"x\" HTTP/1.1" 200 512` + "\n"},
+		{"combined", Combined, `203.0.113.7 - alice [05/Mar/2024:14:07:09 +0900] "GET /search?q=\"x\" HTTP/1.1" 200 512 "http://example.com/" "curl/8.0"` + "\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out syncBuffer
+			l := New(&out, WithFormat(tc.format))
+			l.Log(request(), http.StatusOK, 512, start, 1500*time.Microsecond)
+			l.Close()
+			if got := out.String(); got != tc.want {
+				t.Errorf("expected\n%s\ngot\n%s", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var out syncBuffer
+		l := New(&out, WithFormat(JSON))
+		r := request()
+		r.Header.Set("User-Agent", "bad\xff\n")
+		l.Log(r, http.StatusNotFound, 0, start, 1500*time.Microsecond)
+		l.Close()
+
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(out.String()), &entry); err != nil {
+			t.Fatalf("invalid JSON %q: %v", out.String(), err)
+		}
+		for key, want := range map[string]any{
+			"time": "2024-03-05T14:07:09+09:00", "remote_addr": "203.0.113.7:4321", "method": "GET",
+			"uri": `/search?q="x"`, "proto": "HTTP/1.1", "status": 404.0, "bytes": 0.0, "duration_ms": 1.5,
+			"referer": "http://example.com/", "user_agent": "bad\ufffd\n",
+		} {
+			if entry[key] != want {
+				t.Errorf("%s: expected %v, got %v", key, want, entry[key])
+			}
+		}
+	})
+}
+
+func TestEscaping(t *testing.T) {
+	var out syncBuffer
+	l := New(&out, WithFormat(Combined))
+	r := request()
+	r.Header.Set("User-Agent", "x\" \"forged\n")
+	l.Log(r, http.StatusOK, 0, start, 0)
+	l.Close()
+	if got := out.String(); !strings.HasSuffix(got, ` 200 - "http://example.com/" "x\" \"forged\x0a"`+"\n") {
+		t.Errorf("user agent not escaped: %q", got)
+	}
+}
+
+func TestSamplingAndSkip(t *testing.T) {
+	var out syncBuffer
+	l := New(&out, WithFormat(Common), WithSampling(0), WithSkip(SkipPaths("/healthz")))
+	l.Log(request(), http.StatusOK, 1, start, 0)
+	l.Log(request(), http.StatusInternalServerError, 1, start, 0)
+	l.Log(httptest.NewRequest("GET", "/healthz", nil), http.StatusServiceUnavailable, 1, start, 0)
+	l.Close()
+	l.Log(request(), http.StatusInternalServerError, 1, start, 0)
+
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `" 500 1`) {
+		t.Errorf("expected only the sampled-in error, got %q", out.String())
+	}
+}
+
+// blockingWriter holds up the Logger's goroutine until release is closed.
+type blockingWriter struct{ entered, release chan struct{} }
+
+func (b blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case b.entered <- struct{}{}:
+	default:
+	}
+	<-b.release
+	return len(p), nil
+}
+
+func TestDropped(t *testing.T) {
+	w := blockingWriter{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	l := New(w, WithBufferSize(2))
+	l.Log(request(), http.StatusOK, 1, start, 0)
+	<-w.entered
+	for range 10 {
+		l.Log(request(), http.StatusOK, 1, start, 0)
+	}
+	if n := l.Dropped(); n != 8 {
+		t.Errorf("expected 8 dropped lines, got %d", n)
+	}
+	close(w.release)
+	l.Close()
+}
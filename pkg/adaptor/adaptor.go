@@ -3,7 +3,9 @@ package adaptor
 import (
 	"bufio"
 	"bytes"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -29,9 +31,26 @@ type ResponseWriter struct {
 	wroteHeader bool
 	hijacked    bool
 	chunked     bool
+	written     int64
+	wire        int64
 	body        *bytebufferpool.ByteBuffer
+
+	contMu     sync.Mutex // Orders "100 Continue" before the response. // "100 Continue"를 응답보다 앞에 둡니다.
+	cont       continueState
+	contReader *continueReader
 }
 
+// continueState tracks the interim response to "Expect: 100-continue".
+// continueState는 "Expect: 100-continue"에 대한 중간 응답을 추적합니다.
+type continueState uint8
+
+const (
+	continueNone continueState = iota
+	continuePending
+	continueSent
+	continueSkipped // The response was committed first. // 응답이 먼저 커밋되었습니다.
+)
+
 // rwPool recycles ResponseWriter objects to reduce GC pressure.
 // rwPool은 ResponseWriter 객체를 재활용하여 가비지 컬렉션(GC) 부하를 줄입니다.
 var rwPool = sync.Pool{
@@ -60,6 +79,9 @@ func NewResponseWriter(ctx *appcontext.RequestContext, req *http.Request) *Respo
 	rw.statusCode = 0
 	rw.wroteHeader = false
 	rw.hijacked = false
+	rw.written = 0
+	rw.wire = 0
+	rw.cont = continueNone
 	rw.body = bytebufferpool.Get()
 
 	// No need to re-allocate header map; it is cleared in Release().
@@ -73,6 +95,11 @@ func NewResponseWriter(ctx *appcontext.RequestContext, req *http.Request) *Respo
 func (rw *ResponseWriter) Release() {
 	rw.ctx = nil
 	rw.req = nil
+	// The body outlives the response while the engine drains it. // 엔진이 비우는 동안 바디는 응답보다 오래 삽니다.
+	if rw.contReader != nil {
+		rw.contReader.rw = nil
+		rw.contReader = nil
+	}
 	if rw.body != nil {
 		bytebufferpool.Put(rw.body)
 		rw.body = nil
@@ -105,7 +132,9 @@ func (rw *ResponseWriter) Write(p []byte) (int, error) {
 			rw.statusCode = http.StatusOK
 		}
 	}
-	return rw.body.Write(p)
+	n, err := rw.body.Write(p)
+	rw.written += int64(n)
+	return n, err
 }
 
 // ReadFrom implements io.ReaderFrom for efficient file transfer.
@@ -152,6 +181,8 @@ func (rw *ResponseWriter) ReadFrom(r io.Reader) (n int64, err error) {
 		// 참고: netpollWriterWrapper.Write는 데이터 무결성을 보장하기 위해 플러시를 처리합니다.
 	}
 
+	rw.written += n
+	rw.wire += n
 	return n, err
 }
 
@@ -173,6 +204,7 @@ func (rw *ResponseWriter) Flush() {
 		writer.WriteString(chunkHeader)
 		writer.WriteBinary(rw.body.Bytes())
 		writer.WriteString("\r\n")
+		rw.wire += int64(len(chunkHeader) + rw.body.Len() + 2)
 		rw.body.Reset()
 	}
 	writer.Flush()
@@ -187,10 +219,71 @@ func (rw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 		return nil, nil, errors.New("hijack not allowed after headers written")
 	}
 	rw.hijacked = true
-	conn := rw.ctx.Conn()
+	// The Engine's read deadlines belong to the HTTP exchange, not to the hijacker.
+	// Engine의 읽기 데드라인은 HTTP 교환에 속하며 하이재커에게는 적용되지 않습니다.
+	_ = rw.ctx.Conn().SetReadDeadline(time.Time{})
+	var conn net.Conn = rw.ctx.Conn()
+	if c := appcontext.ConnFromContext(rw.ctx.Req()); c != nil {
+		conn = &hijackedConn{Connection: rw.ctx.Conn(), c: c}
+	}
 	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
 }
 
+// hijackedConn lets the Engine know when a hijacked connection is closed.
+// hijackedConn은 하이재킹된 연결이 닫힐 때 Engine에 알립니다.
+type hijackedConn struct {
+	netpoll.Connection
+	c *appcontext.Conn
+}
+
+func (h *hijackedConn) Close() error {
+	err := h.Connection.Close()
+	_ = h.c.Close()
+	return err
+}
+
+// Committed reports whether the status line and headers have been sent, after which the
+// response can no longer be replaced.
+// Committed는 상태 라인과 헤더가 전송되었는지 보고하며, 전송된 후에는 응답을 바꿀 수 없습니다.
+func (rw *ResponseWriter) Committed() bool {
+	return rw.wroteHeader || rw.hijacked
+}
+
+// Reset discards the buffered status, headers and body so a different response can be sent.
+// It reports false, and does nothing, once the response is committed.
+// Reset은 버퍼링된 상태, 헤더, 바디를 버려 다른 응답을 보낼 수 있게 합니다.
+// 응답이 이미 전송되었다면 아무것도 하지 않고 false를 반환합니다.
+func (rw *ResponseWriter) Reset() bool {
+	if rw.Committed() {
+		return false
+	}
+	clear(rw.header)
+	rw.statusCode = 0
+	rw.chunked = false
+	rw.written = 0
+	rw.body.Reset()
+	return true
+}
+
+// Status returns the status code of the response, or 0 if the handler has not set one.
+// Status는 응답의 상태 코드를 반환하며, 핸들러가 설정하지 않았다면 0을 반환합니다.
+func (rw *ResponseWriter) Status() int {
+	return rw.statusCode
+}
+
+// WireBytes returns the number of response bytes handed to the connection so far, including the
+// status line, headers and chunked framing.
+// WireBytes는 지금까지 연결에 넘긴 응답 바이트 수를 반환하며, 상태 줄, 헤더, 청크 프레이밍을 포함합니다.
+func (rw *ResponseWriter) WireBytes() int64 {
+	return rw.wire
+}
+
+// Written returns the number of body bytes the handler has written.
+// Written은 핸들러가 쓴 바디 바이트 수를 반환합니다.
+func (rw *ResponseWriter) Written() int64 {
+	return rw.written
+}
+
 // Hijacked returns true if the connection has been hijacked.
 // Hijacked는 연결이 하이재킹되었는지 여부를 반환합니다.
 func (rw *ResponseWriter) Hijacked() bool {
@@ -203,6 +296,13 @@ func (rw *ResponseWriter) writeHeaders(writer netpoll.Writer, isStreaming bool)
 	}
 	rw.wroteHeader = true
 
+	// A final response makes "100 Continue" pointless. // 최종 응답이 나가면 "100 Continue"는 의미가 없습니다.
+	rw.contMu.Lock()
+	if rw.cont == continuePending {
+		rw.cont = continueSkipped
+	}
+	rw.contMu.Unlock()
+
 	var buf bytes.Buffer
 
 	if rw.header.Get("Date") == "" {
@@ -250,9 +350,26 @@ func (rw *ResponseWriter) writeHeaders(writer netpoll.Writer, isStreaming bool)
 	}
 	buf.WriteString("\r\n")
 	writer.WriteBinary(buf.Bytes())
+	rw.wire += int64(buf.Len())
 }
 
+// EndResponse completes the response and flushes it to the connection.
+// EndResponse는 응답을 완성하고 연결로 플러시합니다.
 func (rw *ResponseWriter) EndResponse() error {
+	if err := rw.Finish(); err != nil {
+		return err
+	}
+	if rw.hijacked {
+		return nil
+	}
+	return rw.ctx.Conn().Writer().Flush()
+}
+
+// Finish completes the response in the connection's writer without flushing it, so that the
+// Engine can send the responses to pipelined requests together.
+// Finish는 연결의 Writer에 응답을 완성하되 플러시하지 않아, Engine이 파이프라인된 요청들의 응답을
+// 함께 보낼 수 있게 합니다.
+func (rw *ResponseWriter) Finish() error {
 	if rw.hijacked {
 		// Safe cleanup
 		if rw.body != nil {
@@ -269,6 +386,11 @@ func (rw *ResponseWriter) EndResponse() error {
 	isChunked := rw.chunked || rw.header.Get("Transfer-Encoding") == "chunked"
 
 	if !rw.wroteHeader {
+		// A handler that wrote nothing still answers 200, as with net/http.
+		// 아무것도 쓰지 않은 핸들러도 net/http와 같이 200으로 응답합니다.
+		if rw.statusCode == 0 {
+			rw.statusCode = http.StatusOK
+		}
 		rw.writeHeaders(writer, isChunked)
 	}
 
@@ -276,31 +398,105 @@ func (rw *ResponseWriter) EndResponse() error {
 		if rw.body.Len() > 0 {
 			chunkHeader := strconv.FormatInt(int64(rw.body.Len()), 16) + "\r\n"
 			writer.WriteString(chunkHeader)
-			writer.WriteBinary(rw.body.Bytes())
+			writeCopy(writer, rw.body.Bytes())
 			writer.WriteString("\r\n")
+			rw.wire += int64(len(chunkHeader) + rw.body.Len() + 2)
 		}
 		// Fix: ALWAYS send zero chunk if streaming, this was likely the infinite loading bug in v0.0.2
 		writer.WriteString("0\r\n\r\n")
+		rw.wire += 5
 	} else {
 		if rw.body.Len() > 0 {
-			if _, err := writer.WriteBinary(rw.body.Bytes()); err != nil {
+			if err := writeCopy(writer, rw.body.Bytes()); err != nil {
 				bytebufferpool.Put(rw.body)
 				rw.body = nil
 				return err
 			}
+			rw.wire += int64(rw.body.Len())
 		}
 	}
 
-	err := writer.Flush()
-	
-	// Fix: Release buffer AFTER flush to avoid data corruption
-	if rw.body != nil {
-		bytebufferpool.Put(rw.body)
-		rw.body = nil
+	bytebufferpool.Put(rw.body)
+	rw.body = nil
+	return nil
+}
+
+// writeCopy copies p into the writer. WriteBinary may keep a reference to large slices until the
+// next flush, which Finish cannot allow because the body buffer returns to the pool before that.
+// writeCopy는 p를 Writer에 복사합니다. WriteBinary는 큰 슬라이스를 다음 플러시까지 참조할 수 있는데,
+// Finish는 그 전에 바디 버퍼를 풀에 반환하므로 이를 허용할 수 없습니다.
+func writeCopy(writer netpoll.Writer, p []byte) error {
+	buf, err := writer.Malloc(len(p))
+	if err != nil {
+		return err
 	}
-	return err
+	copy(buf, p)
+	return nil
+}
+
+// ExpectContinue defers "100 Continue" for a request sent with "Expect: 100-continue" until the handler
+// first reads its body, as net/http does. Once the response is committed it is no longer sent, and the
+// client may not send the body at all.
+// ExpectContinue는 "Expect: 100-continue"로 보낸 요청의 "100 Continue"를 net/http처럼 핸들러가 바디를 처음 읽을
+// 때까지 미룹니다. 응답이 커밋된 뒤에는 보내지 않으며, 클라이언트가 바디를 아예 보내지 않을 수도 있습니다.
+func (rw *ResponseWriter) ExpectContinue() {
+	if rw.req.Body == nil || rw.req.Body == http.NoBody {
+		return
+	}
+	rw.cont = continuePending
+	rw.contReader = &continueReader{ReadCloser: rw.req.Body, rw: rw}
+	rw.req.Body = rw.contReader
+}
+
+// ContinueWithheld reports whether the request expected "100 Continue" and it has not been sent, so the
+// client may still be holding back the body.
+// ContinueWithheld는 요청이 "100 Continue"를 기대했으나 아직 보내지 않아 클라이언트가 바디를 보류하고 있을 수
+// 있는지 보고합니다.
+func (rw *ResponseWriter) ContinueWithheld() bool {
+	rw.contMu.Lock()
+	defer rw.contMu.Unlock()
+	return rw.cont == continuePending || rw.cont == continueSkipped
 }
 
+// sendContinue writes "100 Continue" if it is still pending. // sendContinue는 아직 대기 중이면 "100 Continue"를 씁니다.
+func (rw *ResponseWriter) sendContinue() error {
+	rw.contMu.Lock()
+	defer rw.contMu.Unlock()
+	if rw.cont != continuePending {
+		return nil
+	}
+	rw.cont = continueSent
+	const line = "HTTP/1.1 100 Continue\r\n\r\n"
+	writer := rw.ctx.Conn().Writer()
+	if _, err := writer.WriteString(line); err != nil {
+		return err
+	}
+	rw.wire += int64(len(line))
+	return writer.Flush()
+}
+
+// continueReader sends "100 Continue" on the first read of the body.
+// continueReader는 바디를 처음 읽을 때 "100 Continue"를 보냅니다.
+type continueReader struct {
+	io.ReadCloser
+	rw   *ResponseWriter // nil once the response is released. // 응답이 해제되면 nil입니다.
+	sent bool
+}
+
+func (c *continueReader) Read(p []byte) (int, error) {
+	if !c.sent && c.rw != nil {
+		c.sent = true
+		if err := c.rw.sendContinue(); err != nil {
+			return 0, err
+		}
+	}
+	return c.ReadCloser.Read(p)
+}
+
+// GetRequest reads the next request from the connection. A malformed request is reported as a
+// *RequestError; other errors come from the connection.
+// GetRequest는 연결에서 다음 요청을 읽습니다. 잘못된 요청은 *RequestError로 보고하며, 그 밖의 오류는 연결에서
+// 발생한 것입니다.
 func GetRequest(ctx *appcontext.RequestContext) (*http.Request, error) {
 	reader := ctx.GetReader()
 	req, err := http.ReadRequest(reader)
@@ -308,20 +504,51 @@ func GetRequest(ctx *appcontext.RequestContext) (*http.Request, error) {
 		if errors.Is(err, io.EOF) {
 			return nil, io.EOF
 		}
-		return nil, errors.New("failed to read request")
+		if reqErr := classifyReadError(err); reqErr != err {
+			return nil, reqErr
+		}
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+	// Derive the request context from the connection context so handlers observe disconnects and draining.
+	// 핸들러가 연결 종료와 드레이닝을 감지할 수 있도록 요청 컨텍스트를 연결 컨텍스트에서 파생합니다.
+	if parent := ctx.Req(); parent != nil {
+		req = req.WithContext(parent)
 	}
 	req.URL.Scheme = "http"
+	// TLS connections expose their negotiated state, mirroring net/http. // TLS 연결은 net/http와 같이 협상된 상태를 노출합니다.
+	if cs, ok := ctx.Conn().(connectionStater); ok {
+		state := cs.ConnectionState()
+		req.TLS = &state
+		req.URL.Scheme = "https"
+	}
 	req.URL.Host = req.Host
 	req.RequestURI = req.URL.RequestURI() // Fix: Ensure RequestURI
 
-	// Fix: RemoteAddr
-	if addr := ctx.Conn().RemoteAddr(); addr != nil {
-		req.RemoteAddr = addr.String()
+	// Fix: RemoteAddr (the PROXY protocol source when the server received one)
+	if addr := ctx.RemoteAddr(); addr != nil {
+		req.RemoteAddr = remoteAddrString(addr)
 	}
 
 	return req, nil
 }
 
+// remoteAddrString formats a peer address for req.RemoteAddr.
+// Unix peers are usually unnamed, so they are reported as "@" instead of an empty string.
+// remoteAddrString은 req.RemoteAddr에 사용할 피어 주소를 만듭니다.
+// Unix 피어는 보통 이름이 없으므로 빈 문자열 대신 "@"로 표시합니다.
+func remoteAddrString(addr net.Addr) string {
+	if ua, ok := addr.(*net.UnixAddr); ok && ua.Name == "" {
+		return "@"
+	}
+	return addr.String()
+}
+
+// connectionStater is implemented by connections that carry a TLS session.
+// connectionStater는 TLS 세션을 가진 연결이 구현합니다.
+type connectionStater interface {
+	ConnectionState() tls.ConnectionState
+}
+
 // netpollWriterWrapper adapts netpoll.Writer to io.Writer.
 // netpollWriterWrapper는 netpoll.Writer를 io.Writer에 맞게 조정합니다.
 type netpollWriterWrapper struct {
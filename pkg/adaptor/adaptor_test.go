@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cloudwego/netpoll"
 	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
@@ -40,6 +41,10 @@ func (m *mockConn) RemoteAddr() net.Addr {
 	}
 }
 
+func (m *mockConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
 // onlyReader implements only io.Reader, hiding io.WriterTo.
 // This forces io.Copy to use ReadFrom instead of WriteTo.
 type onlyReader struct {
@@ -0,0 +1,96 @@
+package adaptor
+
+import (
+	"errors"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// RequestError describes a request that could not be accepted before reaching a handler, with the
+// status it should be answered with.
+// RequestError는 핸들러에 도달하기 전에 받아들일 수 없었던 요청을, 응답해야 할 상태와 함께 설명합니다.
+type RequestError struct {
+	Status int    // 400, 408, 414, 431, 501 or 505. // 400, 408, 414, 431, 501 또는 505입니다.
+	Reason string // A short description that is safe to show the client. // 클라이언트에게 보여도 안전한 짧은 설명입니다.
+	Err    error  // The underlying error, if any. // 원인이 된 오류이며, 없을 수 있습니다.
+}
+
+func (e *RequestError) Error() string {
+	if e.Err == nil {
+		return e.Reason
+	}
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// parseErrors maps the prefixes of http.ReadRequest's errors to the response they call for, as net/http does.
+// parseErrors는 net/http와 같이 http.ReadRequest 오류의 접두사를 그에 맞는 응답으로 대응시킵니다.
+var parseErrors = []struct {
+	prefix string
+	status int
+	reason string
+}{
+	{"malformed HTTP request", http.StatusBadRequest, "malformed request line"},
+	{"invalid method", http.StatusBadRequest, "invalid method"},
+	{"malformed HTTP version", http.StatusBadRequest, "malformed HTTP version"},
+	{"unsupported transfer encoding", http.StatusNotImplemented, "unsupported Transfer-Encoding"},
+	{"too many transfer encodings", http.StatusNotImplemented, "unsupported Transfer-Encoding"},
+	{"bad Content-Length", http.StatusBadRequest, "invalid Content-Length"},
+	{"invalid empty Content-Length", http.StatusBadRequest, "invalid Content-Length"},
+	{"http: message cannot contain multiple Content-Length headers", http.StatusBadRequest, "invalid Content-Length"},
+	{"invalid Trailer key", http.StatusBadRequest, "invalid Trailer"},
+	{"too many Host headers", http.StatusBadRequest, "too many Host headers"},
+}
+
+// classifyReadError turns an error from http.ReadRequest into a *RequestError when the request itself is
+// malformed. Errors from the connection are returned as they are.
+// classifyReadError는 요청 자체가 잘못된 경우 http.ReadRequest의 오류를 *RequestError로 바꿉니다.
+// 연결에서 발생한 오류는 그대로 반환합니다.
+func classifyReadError(err error) error {
+	var pe textproto.ProtocolError
+	if errors.As(err, &pe) {
+		return &RequestError{Status: http.StatusBadRequest, Reason: "malformed headers", Err: err}
+	}
+	msg := err.Error()
+	for _, pe := range parseErrors {
+		if strings.HasPrefix(msg, pe.prefix) {
+			return &RequestError{Status: pe.status, Reason: pe.reason, Err: err}
+		}
+	}
+	return err
+}
+
+// ValidateRequest checks what http.ReadRequest leaves to the server, as net/http does: the protocol
+// version, the Host header required by HTTP/1.1, and header names and values. It returns a
+// *RequestError or nil.
+// ValidateRequest는 net/http와 같이 http.ReadRequest가 서버에 맡기는 부분, 즉 프로토콜 버전, HTTP/1.1이 요구하는
+// Host 헤더, 헤더 이름과 값을 검사합니다. *RequestError 또는 nil을 반환합니다.
+func ValidateRequest(req *http.Request) error {
+	if req.ProtoMajor != 1 {
+		return &RequestError{Status: http.StatusHTTPVersionNotSupported, Reason: "unsupported protocol version"}
+	}
+	hosts, ok := req.Header["Host"]
+	if req.ProtoAtLeast(1, 1) && (!ok || len(hosts) == 0) && req.Method != http.MethodConnect {
+		return &RequestError{Status: http.StatusBadRequest, Reason: "missing required Host header"}
+	}
+	if len(hosts) == 1 && !httpguts.ValidHostHeader(hosts[0]) {
+		return &RequestError{Status: http.StatusBadRequest, Reason: "malformed Host header"}
+	}
+	for k, vv := range req.Header {
+		if !httpguts.ValidHeaderFieldName(k) {
+			return &RequestError{Status: http.StatusBadRequest, Reason: "invalid header name"}
+		}
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				return &RequestError{Status: http.StatusBadRequest, Reason: "invalid header value"}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package appcontext
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/proxyproto"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// connID hands out process-unique connection identifiers.
+// connID는 프로세스 내에서 고유한 연결 식별자를 발급합니다.
+var connID atomic.Uint64
+
+// Conn holds per-connection state shared by the server and the engine across requests.
+// It lives in the connection context created in OnPrepare.
+// Conn은 요청 간에 서버와 엔진이 공유하는 연결별 상태를 담습니다.
+// OnPrepare에서 생성된 연결 컨텍스트에 저장됩니다.
+type Conn struct {
+	conn      netpoll.Connection
+	id        uint64
+	createdAt time.Time
+	state     atomic.Int32 // http.ConnState
+	onState   func(*Conn, http.ConnState)
+	proxy     *proxyproto.Header
+
+	drainOnce sync.Once
+	drain     chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewConn creates the state for a newly accepted connection in http.StateNew.
+// onState, if not nil, is called after every later state change, like http.Server.ConnState.
+// NewConn은 새로 수락된 연결의 상태를 http.StateNew로 생성합니다.
+// onState가 nil이 아니면 http.Server.ConnState처럼 이후 상태가 바뀔 때마다 호출됩니다.
+func NewConn(conn netpoll.Connection, onState func(*Conn, http.ConnState)) *Conn {
+	c := &Conn{
+		conn:      conn,
+		id:        connID.Add(1),
+		createdAt: time.Now(),
+		onState:   onState,
+		drain:     make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+	c.state.Store(int32(http.StateNew))
+	return c
+}
+
+// ID returns the connection identifier.
+// ID는 연결 식별자를 반환합니다.
+func (c *Conn) ID() uint64 {
+	return c.id
+}
+
+// Netpoll returns the underlying netpoll.Connection.
+// Netpoll은 기반 netpoll.Connection을 반환합니다.
+func (c *Conn) Netpoll() netpoll.Connection {
+	return c.conn
+}
+
+// CreatedAt returns when the connection was accepted.
+// CreatedAt은 연결이 수락된 시각을 반환합니다.
+func (c *Conn) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// SetProxyHeader records the PROXY protocol header read at connection start.
+// It must be called before the first request is served.
+// SetProxyHeader는 연결 시작 시 읽은 PROXY 프로토콜 헤더를 기록합니다.
+// 첫 요청을 처리하기 전에 호출해야 합니다.
+func (c *Conn) SetProxyHeader(h *proxyproto.Header) {
+	c.proxy = h
+}
+
+// ProxyHeader returns the PROXY protocol header, or nil if none was received.
+// ProxyHeader는 PROXY 프로토콜 헤더를 반환하며, 받은 헤더가 없으면 nil을 반환합니다.
+func (c *Conn) ProxyHeader() *proxyproto.Header {
+	return c.proxy
+}
+
+// RemoteAddr returns the client address, preferring the source relayed by a PROXY header.
+// RemoteAddr는 클라이언트 주소를 반환하며, PROXY 헤더로 전달된 출발지 주소를 우선합니다.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.proxy != nil && c.proxy.Source != nil {
+		return c.proxy.Source
+	}
+	return c.conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to, preferring the destination relayed by a PROXY header.
+// LocalAddr는 클라이언트가 접속한 주소를 반환하며, PROXY 헤더로 전달된 목적지 주소를 우선합니다.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.proxy != nil && c.proxy.Destination != nil {
+		return c.proxy.Destination
+	}
+	return c.conn.LocalAddr()
+}
+
+// State returns the current lifecycle state.
+// State는 현재 생명주기 상태를 반환합니다.
+func (c *Conn) State() http.ConnState {
+	return http.ConnState(c.state.Load())
+}
+
+// SetState sets the lifecycle state.
+// As in net/http, http.StateHijacked and http.StateClosed are terminal and never left.
+// SetState는 생명주기 상태를 설정합니다.
+// net/http와 같이 http.StateHijacked와 http.StateClosed는 종료 상태이며 벗어나지 않습니다.
+func (c *Conn) SetState(state http.ConnState) {
+	for {
+		old := http.ConnState(c.state.Load())
+		if old == state || old == http.StateHijacked || old == http.StateClosed {
+			return
+		}
+		if c.CompareAndSwapState(old, state) {
+			return
+		}
+	}
+}
+
+// CompareAndSwapState moves the connection from old to new if it is still in old.
+// CompareAndSwapState는 연결이 아직 old 상태라면 new 상태로 전환합니다.
+func (c *Conn) CompareAndSwapState(old, new http.ConnState) bool {
+	if !c.state.CompareAndSwap(int32(old), int32(new)) {
+		return false
+	}
+	if c.onState != nil && old != new {
+		c.onState(c, new)
+	}
+	return true
+}
+
+// Close closes the connection and wakes a ServeConn loop parked on it after a hijack.
+// netpoll defers close callbacks until OnRequest returns, so the loop cannot rely on them.
+// Close는 연결을 닫고, 하이재킹 이후 대기 중인 ServeConn 루프를 깨웁니다.
+// netpoll은 OnRequest가 반환될 때까지 종료 콜백을 미루므로 루프는 콜백에 의존할 수 없습니다.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.conn.Close()
+}
+
+// Closed returns a channel closed by Close.
+// Closed는 Close가 호출되면 닫히는 채널을 반환합니다.
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// StartDrain asks the connection to finish its current work and close.
+// StartDrain은 연결이 현재 작업을 마치고 닫히도록 요청합니다.
+func (c *Conn) StartDrain() {
+	c.drainOnce.Do(func() {
+		close(c.drain)
+	})
+}
+
+// Draining reports whether StartDrain has been called.
+// Draining은 StartDrain이 호출되었는지 보고합니다.
+func (c *Conn) Draining() bool {
+	select {
+	case <-c.drain:
+		return true
+	default:
+		return false
+	}
+}
+
+// DrainNotify returns a channel closed when the connection starts draining.
+// Long-lived handlers such as SSE streams or hijacked connections should wrap up when it fires.
+// DrainNotify는 연결이 드레이닝을 시작할 때 닫히는 채널을 반환합니다.
+// SSE 스트림이나 하이재킹된 연결처럼 오래 유지되는 핸들러는 이 신호에 맞춰 마무리해야 합니다.
+func (c *Conn) DrainNotify() <-chan struct{} {
+	return c.drain
+}
+
+type connKeyStruct struct{}
+
+var connKey = connKeyStruct{}
+
+// WithConn returns a copy of ctx carrying c.
+// WithConn은 c를 담은 ctx의 복사본을 반환합니다.
+func WithConn(ctx context.Context, c *Conn) context.Context {
+	return context.WithValue(ctx, connKey, c)
+}
+
+// ConnFromContext returns the Conn stored in ctx, or nil.
+// Request contexts derive from the connection context, so handlers can call it with r.Context().
+// ConnFromContext는 ctx에 저장된 Conn을 반환하며, 없으면 nil을 반환합니다.
+// 요청 컨텍스트는 연결 컨텍스트에서 파생되므로 핸들러는 r.Context()로 호출할 수 있습니다.
+func ConnFromContext(ctx context.Context) *Conn {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(connKey).(*Conn)
+	return c
+}
@@ -2,9 +2,14 @@ package appcontext
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"io"
+	"net"
 	"sync"
 
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/proxyproto"
+
 	"github.com/cloudwego/netpoll"
 )
 
@@ -14,6 +19,35 @@ type RequestContext struct {
 	conn   netpoll.Connection
 	req    context.Context // Parent context. // 부모 컨텍스트
 	reader *bufio.Reader
+	src    limitedSource
+	bound  bool // reader has been reset for this request. // 이 요청을 위해 reader가 초기화되었습니다.
+}
+
+// limitedSource feeds the reader from the connection, stopping at the limit set by SetReadLimit.
+// limitedSource는 연결에서 reader로 데이터를 공급하며, SetReadLimit으로 설정한 한도에서 멈춥니다.
+type limitedSource struct {
+	r         io.Reader
+	remaining int64 // Negative without a limit. // 한도가 없으면 음수입니다.
+	hit       bool
+	newline   bool // A line ended since the limit was set. // 한도를 설정한 뒤 줄이 끝났습니다.
+}
+
+func (l *limitedSource) Read(p []byte) (int, error) {
+	if l.remaining == 0 {
+		l.hit = true
+		return 0, io.EOF
+	}
+	if l.remaining > 0 && int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	if l.remaining > 0 {
+		l.remaining -= int64(n)
+	}
+	if !l.newline && bytes.IndexByte(p[:n], '\n') >= 0 {
+		l.newline = true
+	}
+	return n, err
 }
 
 // pool recycles RequestContext objects to reduce GC pressure.
@@ -45,6 +79,8 @@ func (c *RequestContext) Release() {
 func (c *RequestContext) reset() {
 	c.conn = nil
 	c.req = nil
+	c.bound = false
+	c.src = limitedSource{}
 	// reader is not nil-ed for reuse. // reader는 재사용을 위해 nil로 초기화하지 않습니다.
 }
 
@@ -60,13 +96,87 @@ func (c *RequestContext) Req() context.Context {
 	return c.req
 }
 
-// GetReader returns a reusable bufio.Reader.
-// GetReader는 재사용 가능한 bufio.Reader를 반환합니다.
+// RemoteAddr returns the client address, which is the PROXY protocol source when one was received.
+// RemoteAddr는 클라이언트 주소를 반환하며, PROXY 프로토콜 헤더를 받았다면 그 출발지 주소입니다.
+func (c *RequestContext) RemoteAddr() net.Addr {
+	if conn := ConnFromContext(c.req); conn != nil {
+		return conn.RemoteAddr()
+	}
+	return c.conn.RemoteAddr()
+}
+
+// LocalAddr returns the server address, which is the PROXY protocol destination when one was received.
+// LocalAddr는 서버 주소를 반환하며, PROXY 프로토콜 헤더를 받았다면 그 목적지 주소입니다.
+func (c *RequestContext) LocalAddr() net.Addr {
+	if conn := ConnFromContext(c.req); conn != nil {
+		return conn.LocalAddr()
+	}
+	return c.conn.LocalAddr()
+}
+
+// ProxyHeader returns the PROXY protocol header of the connection, or nil.
+// ProxyHeader는 연결의 PROXY 프로토콜 헤더를 반환하며, 없으면 nil을 반환합니다.
+func (c *RequestContext) ProxyHeader() *proxyproto.Header {
+	if conn := ConnFromContext(c.req); conn != nil {
+		return conn.ProxyHeader()
+	}
+	return nil
+}
+
+// GetReader returns the request's bufio.Reader. The reusable reader is reset on the first call
+// for a request, so later calls keep what it has already buffered.
+// GetReader는 요청의 bufio.Reader를 반환합니다. 재사용되는 reader는 요청마다 첫 호출에서만 초기화되므로
+// 이후 호출은 이미 버퍼링된 데이터를 유지합니다.
 func (c *RequestContext) GetReader() *bufio.Reader {
+	if c.bound {
+		return c.reader
+	}
+	c.src = limitedSource{r: c.conn, remaining: -1}
 	if c.reader == nil {
-		c.reader = bufio.NewReader(c.conn)
+		c.reader = bufio.NewReader(&c.src)
 	} else {
-		c.reader.Reset(c.conn)
+		c.reader.Reset(&c.src)
 	}
+	c.bound = true
 	return c.reader
-}
\ No newline at end of file
+}
+
+// SetReadLimit lets the reader take at most n more bytes from the connection, after which it reports
+// io.EOF; a negative n removes the limit. Bytes the reader has already buffered are not counted.
+// SetReadLimit은 reader가 연결에서 최대 n바이트를 더 가져오게 하며, 그 뒤에는 io.EOF를 보고합니다.
+// n이 음수이면 한도를 없앱니다. reader가 이미 버퍼링한 바이트는 세지 않습니다.
+func (c *RequestContext) SetReadLimit(n int64) {
+	c.src.remaining = n
+	if n >= 0 {
+		c.src.hit = false
+		c.src.newline = false
+		if c.reader != nil {
+			buffered, _ := c.reader.Peek(c.reader.Buffered())
+			c.src.newline = bytes.IndexByte(buffered, '\n') >= 0
+		}
+	}
+}
+
+// ReadLimitReached reports whether the reader stopped at the last limit set by SetReadLimit, even if
+// the limit has been removed since.
+// ReadLimitReached는 reader가 SetReadLimit으로 마지막에 설정한 한도에서 멈췄는지 보고하며, 이후 한도가
+// 제거되었더라도 마찬가지입니다.
+func (c *RequestContext) ReadLimitReached() bool {
+	return c.src.hit
+}
+
+// LineEnded reports whether the reader had received the end of a line, buffered or not, since the
+// last limit set by SetReadLimit. When the limit is reached without one, the first line was too long.
+// LineEnded는 SetReadLimit으로 마지막 한도를 설정한 뒤 버퍼링 여부와 관계없이 reader가 줄의 끝을 받았는지
+// 보고합니다. 줄의 끝 없이 한도에 도달했다면 첫 줄이 너무 긴 것입니다.
+func (c *RequestContext) LineEnded() bool {
+	return c.src.newline
+}
+
+// Reader returns the bufio.Reader prepared by the last GetReader call without resetting it,
+// so data it has already buffered is preserved.
+// Reader는 마지막 GetReader 호출로 준비된 bufio.Reader를 초기화하지 않고 반환하므로,
+// 이미 버퍼링된 데이터가 보존됩니다.
+func (c *RequestContext) Reader() *bufio.Reader {
+	return c.reader
+}
@@ -53,9 +53,23 @@ type Pool struct {
 	defaultSize uint64
 	maxSize     uint64
 
+	hits   uint64
+	misses uint64
+
 	pool sync.Pool
 }
 
+// PoolStats is a snapshot of a pool's counters and calibrated sizes.
+type PoolStats struct {
+	// Hits and Misses count Get calls that reused a pooled buffer or allocated a new one.
+	Hits   uint64
+	Misses uint64
+	// DefaultSize is the capacity of newly allocated buffers and MaxSize the largest
+	// capacity kept on Put, as chosen by the last calibration. Both are 0 before it.
+	DefaultSize uint64
+	MaxSize     uint64
+}
+
 var defaultPool Pool
 
 // Get returns an empty byte buffer from the pool.
@@ -65,6 +79,9 @@ var defaultPool Pool
 // management.
 func Get() *ByteBuffer { return defaultPool.Get() }
 
+// Stats returns the counters of the default pool.
+func Stats() PoolStats { return defaultPool.Stats() }
+
 // Get returns new byte buffer with zero length.
 //
 // The byte buffer may be returned to the pool via Put after the use
@@ -72,8 +89,10 @@ func Get() *ByteBuffer { return defaultPool.Get() }
 func (p *Pool) Get() *ByteBuffer {
 	v := p.pool.Get()
 	if v != nil {
+		atomic.AddUint64(&p.hits, 1)
 		return v.(*ByteBuffer)
 	}
+	atomic.AddUint64(&p.misses, 1)
 	return &ByteBuffer{
 		B: make([]byte, 0, atomic.LoadUint64(&p.defaultSize)),
 	}
@@ -102,6 +121,16 @@ func (p *Pool) Put(b *ByteBuffer) {
 	}
 }
 
+// Stats returns a snapshot of the pool's counters and calibrated sizes.
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		Hits:        atomic.LoadUint64(&p.hits),
+		Misses:      atomic.LoadUint64(&p.misses),
+		DefaultSize: atomic.LoadUint64(&p.defaultSize),
+		MaxSize:     atomic.LoadUint64(&p.maxSize),
+	}
+}
+
 func (p *Pool) calibrate() {
 	if !atomic.CompareAndSwapUint64(&p.calibrating, 0, 1) {
 		return
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/accesslog"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/adaptor"
+)
+
+// WithAccessLog logs every request to l once its response is finished, including requests shed,
+// timed out or aborted by the engine. For HTTP/1.x the byte count is what was written to the connection,
+// status line, headers and chunked framing included; for HTTP/2 it is the response body only.
+// Hijacked requests are not logged. The Engine does not close l.
+// WithAccessLog는 응답이 끝난 모든 요청을 l에 기록하며, 엔진이 거절하거나 타임아웃 또는 중단시킨 요청도 포함합니다.
+// HTTP/1.x의 바이트 수는 상태 줄, 헤더, 청크 프레이밍을 포함해 연결에 쓴 양이며, HTTP/2에서는 응답 바디만 셉니다.
+// 하이재킹된 요청은 기록하지 않습니다. Engine은 l을 닫지 않습니다.
+func WithAccessLog(l *accesslog.Logger) Option {
+	return func(e *Engine) {
+		e.accessLog = l
+	}
+}
+
+// logAccess records a finished HTTP/1.x request. // logAccess는 완료된 HTTP/1.x 요청을 기록합니다.
+func (e *Engine) logAccess(req *http.Request, w *adaptor.ResponseWriter, start time.Time) {
+	if e.accessLog == nil || w.Hijacked() {
+		return
+	}
+	e.accessLog.Log(req, w.Status(), w.WireBytes(), start, time.Since(start))
+}
+
+// logStream records a finished HTTP/2 request. // logStream은 완료된 HTTP/2 요청을 기록합니다.
+func (e *Engine) logStream(req *http.Request, rec *streamRecorder, start time.Time) {
+	if e.accessLog == nil {
+		return
+	}
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	e.accessLog.Log(req, status, rec.written, start, time.Since(start))
+}
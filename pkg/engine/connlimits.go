@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+)
+
+// WithMaxRequestsPerConn closes an HTTP/1.x keep-alive connection after it has served n requests, so
+// clients reconnect and a load balancer can move them to new backends. The last response carries
+// "Connection: close" and the connection closes once it is sent.
+// WithMaxRequestsPerConn은 HTTP/1.x keep-alive 연결이 n개의 요청을 처리하면 닫아, 클라이언트가 다시 연결하고
+// 로드 밸런서가 이를 새 백엔드로 옮길 수 있게 합니다. 마지막 응답에는 "Connection: close"가 붙으며 전송 후 연결이 닫힙니다.
+func WithMaxRequestsPerConn(n int) Option {
+	return func(e *Engine) {
+		e.maxConnRequests = n
+	}
+}
+
+// WithMaxConnAge closes an HTTP/1.x keep-alive connection after the first response it sends once it is
+// older than d, like WithMaxRequestsPerConn. Each connection's limit is shortened by a random amount up
+// to jitter, so connections opened together are not all closed at once. The age is checked when a
+// request arrives; an idle connection is left to the idle timeout.
+// WithMaxConnAge는 HTTP/1.x keep-alive 연결이 d보다 오래되면 그 뒤 처음 보내는 응답 이후에 WithMaxRequestsPerConn처럼
+// 닫습니다. 각 연결의 한도는 최대 jitter만큼 무작위로 줄어들어, 함께 열린 연결들이 한꺼번에 닫히지 않습니다.
+// 연령은 요청이 도착할 때 검사하며, 유휴 연결은 유휴 타임아웃에 맡겨집니다.
+func WithMaxConnAge(d, jitter time.Duration) Option {
+	return func(e *Engine) {
+		e.maxConnAge = d
+		e.maxConnAgeJitter = jitter
+	}
+}
+
+// connLimits tracks how close a connection is to its request cap and maximum age.
+// connLimits는 연결이 요청 수 한도와 최대 연령에 얼마나 가까운지 추적합니다.
+type connLimits struct {
+	remaining int       // Requests left; negative without a cap. // 남은 요청 수이며, 한도가 없으면 음수입니다.
+	expires   time.Time // Zero without a maximum age. // 최대 연령이 없으면 0입니다.
+	reached   bool
+}
+
+// newConnLimits starts the limits for a connection accepted at the time c records, or now without c.
+// newConnLimits는 c가 기록한 시각, c가 없으면 지금 수락된 연결의 한도를 시작합니다.
+func (e *Engine) newConnLimits(c *appcontext.Conn) connLimits {
+	l := connLimits{remaining: -1}
+	if e.maxConnRequests > 0 {
+		l.remaining = e.maxConnRequests
+	}
+	if e.maxConnAge > 0 {
+		born := time.Now()
+		if c != nil {
+			born = c.CreatedAt()
+		}
+		age := e.maxConnAge
+		if e.maxConnAgeJitter > 0 {
+			age -= rand.N(min(e.maxConnAgeJitter, age))
+		}
+		l.expires = born.Add(age)
+	}
+	return l
+}
+
+// admit counts req against the limits. When either is reached, req is marked as the connection's last.
+// admit은 req를 한도에 반영합니다. 어느 한도든 도달하면 req를 연결의 마지막 요청으로 표시합니다.
+func (l *connLimits) admit(req *http.Request) {
+	if l.remaining > 0 {
+		l.remaining--
+		l.reached = l.reached || l.remaining == 0
+	}
+	if !l.expires.IsZero() && !time.Now().Before(l.expires) {
+		l.reached = true
+	}
+	if l.reached {
+		req.Close = true
+	}
+}
@@ -2,13 +2,18 @@ package engine
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/accesslog"
 	"github.com/DevNewbie1826/http-over-netpoll/pkg/adaptor"
 	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/h2"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/metrics"
 
 	"github.com/cloudwego/netpoll"
 )
@@ -17,108 +22,520 @@ import (
 // Option은 Engine 설정을 위한 함수 타입입니다.
 type Option func(*Engine)
 
-// WithRequestTimeout sets the request processing timeout.
-// WithRequestTimeout은 요청 처리 타임아웃을 설정합니다.
+// WithRequestTimeout bounds how long an HTTP/1.x handler may take, like http.TimeoutHandler.
+// When it expires before the response is committed, the buffered response is discarded and the
+// timeout response is sent instead (see WithTimeoutResponse); the handler's context is cancelled and
+// its later writes fail with http.ErrHandlerTimeout. HTTP/2 handlers only get the context deadline.
+// WithRequestTimeout은 http.TimeoutHandler처럼 HTTP/1.x 핸들러의 실행 시간을 제한합니다.
+// 응답이 커밋되기 전에 만료되면 버퍼링된 응답을 버리고 대신 타임아웃 응답을 보내며(WithTimeoutResponse 참고),
+// 핸들러의 컨텍스트는 취소되고 이후 쓰기는 http.ErrHandlerTimeout으로 실패합니다. HTTP/2 핸들러에는
+// 컨텍스트 데드라인만 적용됩니다.
 func WithRequestTimeout(d time.Duration) Option {
 	return func(e *Engine) {
 		e.requestTimeout = d
 	}
 }
 
+// WithHTTP2 enables HTTP/2: h2 over TLS via ALPN, and h2c via prior knowledge or "Upgrade: h2c".
+// WithHTTP2는 HTTP/2를 활성화합니다: TLS에서는 ALPN을 통한 h2, 평문에서는 prior knowledge 또는 "Upgrade: h2c"를 통한 h2c.
+func WithHTTP2(opts ...h2.Option) Option {
+	return func(e *Engine) {
+		e.http2 = true
+		e.http2Opts = opts
+	}
+}
+
+// WithMetrics records each HTTP/1.x request's status class, latency and body sizes in m.
+// Hijacked requests are not recorded.
+// WithMetrics는 각 HTTP/1.x 요청의 상태 클래스, 지연 시간, 바디 크기를 m에 기록합니다.
+// 하이재킹된 요청은 기록하지 않습니다.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(e *Engine) {
+		e.metrics = m
+	}
+}
+
+// WithLogger sets the logger for handler panics, malformed requests and failed writes.
+// Entries carry the remote address, connection id, method and path when they are known.
+// The default is slog.Default().
+// WithLogger는 핸들러 패닉, 잘못된 요청, 실패한 쓰기를 기록할 로거를 설정합니다.
+// 항목에는 알 수 있는 경우 원격 주소, 연결 ID, 메서드, 경로가 포함됩니다. 기본값은 slog.Default()입니다.
+func WithLogger(l *slog.Logger) Option {
+	return func(e *Engine) {
+		e.logger = l
+	}
+}
+
 // Engine is the core structure for processing HTTP requests.
 // Engine은 HTTP 요청을 처리하는 핵심 구조체입니다.
 type Engine struct {
 	Handler        http.Handler
 	requestTimeout time.Duration
+	http2          bool
+	http2Opts      []h2.Option
+	h2             *h2.Server
+	metrics        *metrics.Metrics
+	accessLog      *accesslog.Logger
+	logger         *slog.Logger
+
+	idleTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	bodyReadTimeout   time.Duration
+	minBodyRate       int
+	minBodyRateGrace  time.Duration
+
+	maxHeaderBytes int
+	maxBodyBytes   int64
+	maxDrainBytes  int64
+
+	maxConnRequests  int
+	maxConnAge       time.Duration
+	maxConnAgeJitter time.Duration
+
+	pipelineConcurrency int
+	pool                *executionPool
+
+	timeoutCode         int
+	timeoutBody         string
+	panicHandler        PanicHandler
+	requestErrorHandler RequestErrorHandler
+
+	continuePolicy ContinuePolicy
+
+	middleware []Middleware
+	chain      HandlerFunc // nil without middleware. // 미들웨어가 없으면 nil입니다.
 }
 
 // NewEngine creates a new Engine.
 // NewEngine은 새로운 Engine을 생성합니다.
 func NewEngine(handler http.Handler, opts ...Option) *Engine {
 	e := &Engine{
-		Handler: handler,
+		Handler:        handler,
+		maxHeaderBytes: http.DefaultMaxHeaderBytes,
+		maxDrainBytes:  defaultMaxDrainBytes,
 	}
 	for _, opt := range opts {
 		opt(e)
 	}
+	if e.http2 {
+		e.h2 = h2.NewServer(http.HandlerFunc(e.serveStream), e.http2Opts...)
+	}
 	return e
 }
 
+// log returns the configured logger, falling back to slog.Default() at call time.
+// log는 설정된 로거를 반환하며, 없으면 호출 시점의 slog.Default()를 사용합니다.
+func (e *Engine) log() *slog.Logger {
+	if e.logger != nil {
+		return e.logger
+	}
+	return slog.Default()
+}
+
+// connLogger returns the logger with the connection's remote address and id attached.
+// connLogger는 연결의 원격 주소와 ID가 추가된 로거를 반환합니다.
+func (e *Engine) connLogger(ctx *appcontext.RequestContext) *slog.Logger {
+	l := e.log()
+	if addr := ctx.RemoteAddr(); addr != nil {
+		l = l.With("remote_addr", addr.String())
+	}
+	if c := appcontext.ConnFromContext(ctx.Req()); c != nil {
+		l = l.With("conn_id", c.ID())
+	}
+	return l
+}
+
+// HTTP2Enabled reports whether the Engine accepts HTTP/2 connections.
+// HTTP2Enabled는 Engine이 HTTP/2 연결을 수락하는지 보고합니다.
+func (e *Engine) HTTP2Enabled() bool {
+	return e.h2 != nil
+}
+
 // ServeConn is used as netpoll's OnRequest callback.
 // ServeConn은 netpoll의 OnRequest 콜백으로 사용됩니다.
 func (e *Engine) ServeConn(ctx context.Context, conn netpoll.Connection) error {
+	// Connections that negotiated h2 through ALPN never speak HTTP/1.x.
+	// ALPN으로 h2를 협상한 연결은 HTTP/1.x를 사용하지 않습니다.
+	c := appcontext.ConnFromContext(ctx)
+	if e.h2 != nil {
+		if cs, ok := conn.(interface{ ConnectionState() tls.ConnectionState }); ok && cs.ConnectionState().NegotiatedProtocol == h2.NextProtoTLS {
+			if c != nil {
+				c.SetState(http.StateActive)
+			}
+			return e.h2.ServeConn(ctx, conn, conn)
+		}
+	}
+
+	// The reader lives as long as the connection so that pipelined requests it has buffered are kept.
+	// 버퍼링된 파이프라인 요청이 유지되도록 reader는 연결이 살아 있는 동안 유지됩니다.
+	requestContext := appcontext.NewRequestContext(conn, ctx)
+	defer requestContext.Release()
+	reader := requestContext.GetReader()
+	limits := e.newConnLimits(c)
+
+	var next *http.Request // Read while collecting a pipelined batch. // 파이프라인 배치를 모으는 중에 읽은 요청입니다.
 	for {
-		requestContext := appcontext.NewRequestContext(conn, ctx)
+		if err := flushPending(conn, reader); err != nil {
+			e.connLogger(requestContext).Debug("failed to write response", "error", err)
+			return err
+		}
 
-		req, hijacked, err := e.handleRequest(requestContext)
+		req := next
+		next = nil
+		if req == nil {
+			// Wait for the first byte of the request under the idle timeout, then read the headers under theirs.
+			// 유휴 타임아웃 안에서 요청의 첫 바이트를 기다린 뒤, 헤더 타임아웃 안에서 헤더를 읽습니다.
+			if e.idleTimeout > 0 {
+				_ = conn.SetReadDeadline(time.Now().Add(e.idleTimeout))
+			} else if e.bodyReadTimeout > 0 || e.minBodyRate > 0 {
+				_ = conn.SetReadDeadline(time.Time{}) // Drop the previous body's deadline. // 이전 바디의 데드라인을 제거합니다.
+			}
+			if _, err := reader.Peek(1); err != nil {
+				if e.idleTimeout > 0 && isTimeout(err) {
+					_ = conn.Close()
+					return nil
+				}
+				if errors.Is(err, io.EOF) {
+					return io.EOF
+				}
+				return err
+			}
+
+			var err error
+			if req, err = e.readRequest(requestContext); err != nil {
+				return e.failRequest(requestContext, err)
+			}
+		}
+
+		// Claim the connection for this request; a shutdown may have closed it while it was idle.
+		// 이 요청을 위해 연결을 점유합니다. 유휴 상태일 때 종료 절차가 연결을 닫았을 수 있습니다.
+		if c != nil && !c.CompareAndSwapState(http.StateIdle, http.StateActive) && !c.CompareAndSwapState(http.StateNew, http.StateActive) {
+			return nil
+		}
 
+		// Cleartext HTTP/2 takes over the connection for good.
+		// 평문 HTTP/2는 연결을 완전히 넘겨받습니다.
+		if e.h2 != nil && (h2.IsPriorKnowledge(req) || h2.IsUpgrade(req)) {
+			if err := conn.Writer().Flush(); err != nil {
+				return err
+			}
+			if h2.IsPriorKnowledge(req) {
+				return e.h2.ServePriorKnowledge(ctx, conn, reader)
+			}
+			return e.h2.ServeUpgrade(ctx, conn, reader, req)
+		}
+
+		limits.admit(req)
+		var hijacked bool
+		var err error
+		if e.pipelineConcurrency > 1 && concurrentSafe(req) && requestBuffered(reader) {
+			req, next, err = e.serveBatch(requestContext, req, &limits)
+		} else {
+			hijacked, err = e.handleRequest(requestContext, req)
+		}
 		if err != nil {
-			requestContext.Release()
 			return err
 		}
 
 		if hijacked {
-			<-ctx.Done()
+			// Stay in OnRequest so netpoll does not hand the hijacked connection's data back to us.
+			// netpoll이 하이재킹된 연결의 데이터를 다시 넘기지 않도록 OnRequest에 머무릅니다.
+			if c == nil {
+				<-ctx.Done()
+				return nil
+			}
+			c.SetState(http.StateHijacked)
+			select {
+			case <-ctx.Done():
+			case <-c.Closed():
+			}
 			return nil
 		}
 
-		// Body Draining: Read and discard remaining body data for the next request.
-		// Body Draining: 다음 요청을 위해 남은 바디 데이터를 읽어서 버립니다.
-		if req.Body != nil {
-			_, _ = io.Copy(io.Discard, req.Body)
-			_ = req.Body.Close()
+		// Body Draining: Read and discard remaining body data for the next request, up to the drain limit.
+		// Body Draining: 다음 요청을 위해 남은 바디 데이터를 비우기 한도까지 읽어서 버립니다.
+		if req.Body != nil && !e.drainBody(req.Body) {
+			_ = conn.Writer().Flush()
+			_ = conn.Close()
+			return errBodyTooLarge
 		}
 
-		requestContext.Release()
+		// A draining connection closes after its in-flight response instead of waiting for the next request.
+		// 드레이닝 중인 연결은 다음 요청을 기다리지 않고 진행 중인 응답 이후에 닫힙니다.
+		if c != nil {
+			c.SetState(http.StateIdle)
+			if c.Draining() {
+				_ = conn.Writer().Flush()
+				_ = conn.Close()
+				return nil
+			}
+		}
+
+		// The connection reached its request cap or maximum age; its last response said so.
+		// 연결이 요청 수 한도나 최대 연령에 도달했으며, 마지막 응답이 이를 알렸습니다.
+		if limits.reached {
+			_ = conn.Writer().Flush()
+			_ = conn.Close()
+			return nil
+		}
 
 		// Keep-alive logic: Decides whether to close the connection based on the request.
 		// keep-alive 로직: 요청에 따라 연결을 닫을지 결정합니다.
 		if req.Close || req.Header.Get("Connection") == "close" {
-			return nil
+			return conn.Writer().Flush()
 		}
 	}
 }
 
-// handleRequest processes a single HTTP request and returns the processed request object and hijacking status.
-// handleRequest는 단일 HTTP 요청을 처리하고, 처리된 요청 객체와 하이재킹 여부를 반환합니다.
-func (e *Engine) handleRequest(ctx *appcontext.RequestContext) (*http.Request, bool, error) {
+// readRequest reads the next request's headers under the header timeout.
+// readRequest는 헤더 타임아웃 안에서 다음 요청의 헤더를 읽습니다.
+func (e *Engine) readRequest(ctx *appcontext.RequestContext) (*http.Request, error) {
+	conn := ctx.Conn()
+	if e.readHeaderTimeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(e.readHeaderTimeout))
+	}
+	// Count from the start of the request, already buffered or not, and like net/http leave room for what
+	// the reader buffers past the headers.
+	// 이미 버퍼링되었든 아니든 요청의 시작부터 세며, net/http와 같이 reader가 헤더 너머로 버퍼링하는 만큼 여유를 둡니다.
+	if e.maxHeaderBytes > 0 {
+		ctx.SetReadLimit(int64(e.maxHeaderBytes + 4096 - ctx.Reader().Buffered()))
+	}
 	req, err := adaptor.GetRequest(ctx)
-	if err != nil {
-		return nil, false, err
+	if e.maxHeaderBytes > 0 {
+		ctx.SetReadLimit(-1)
+	}
+	if e.idleTimeout > 0 || e.readHeaderTimeout > 0 {
+		_ = conn.SetReadDeadline(time.Time{})
+	}
+	if err == nil {
+		if err = e.validateRequest(req); err != nil {
+			return nil, err
+		}
 	}
+	return req, err
+}
 
+// failRequest ends the connection after a request could not be read, returning err for ServeConn.
+// A malformed or stalled request is answered first and reported as a *adaptor.RequestError.
+// failRequest는 요청을 읽지 못한 연결을 종료하고, ServeConn이 반환할 err를 돌려줍니다.
+// 잘못되었거나 멈춘 요청에는 먼저 응답하며 *adaptor.RequestError로 보고합니다.
+func (e *Engine) failRequest(ctx *appcontext.RequestContext, err error) error {
+	conn := ctx.Conn()
+	// The request has started, so the client is told why it is being dropped. Answers to earlier
+	// pipelined requests are already in the writer and go out first.
+	// 요청이 이미 시작되었으므로, 클라이언트에게 연결을 끊는 이유를 알립니다. 앞선 파이프라인 요청에 대한 응답은
+	// 이미 Writer에 있으므로 먼저 나갑니다.
+	if reqErr := requestError(ctx, err); reqErr != nil {
+		e.writeRequestError(ctx, reqErr)
+		_ = conn.Close()
+		e.connLogger(ctx).Warn("failed to read request", "status", reqErr.Status, "error", reqErr)
+		return reqErr
+	}
+	_ = conn.Writer().Flush() // Answers to earlier pipelined requests. // 앞선 파이프라인 요청에 대한 응답입니다.
+	// EOF is the peer closing between requests. // EOF는 피어가 요청 사이에 연결을 닫은 것입니다.
+	if err != io.EOF {
+		e.connLogger(ctx).Warn("failed to read request", "error", err)
+	}
+	return err
+}
+
+// handleRequest processes a single HTTP request and returns the hijacking status.
+// handleRequest는 단일 HTTP 요청을 처리하고 하이재킹 여부를 반환합니다.
+func (e *Engine) handleRequest(ctx *appcontext.RequestContext, req *http.Request) (bool, error) {
 	respWriter := adaptor.NewResponseWriter(ctx, req)
 	defer respWriter.Release()
 
-	// Apply Request Timeout.
-	// 요청 타임아웃 적용.
-	var cancel context.CancelFunc
-	if e.requestTimeout > 0 {
-		var timeoutCtx context.Context
-		timeoutCtx, cancel = context.WithTimeout(req.Context(), e.requestTimeout)
-		req = req.WithContext(timeoutCtx)
+	accepted := e.checkBodySize(respWriter, req) && e.checkExpect(respWriter, req)
+	sized := e.limitBody(req)
+	timed := e.wrapBody(ctx.Conn(), req)
+
+	var start time.Time
+	if e.metrics != nil || e.accessLog != nil {
+		start = time.Now()
+	}
+	var body *countingBody
+	if e.metrics != nil {
+		if req.Body != nil && req.Body != http.NoBody {
+			body = &countingBody{ReadCloser: req.Body}
+			req.Body = body
+		}
 	}
 
-	// Panic Recovery
-	// 패닉 복구
-	func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("[Panic] Recovered in handler: %v", r)
-				respWriter.WriteHeader(http.StatusInternalServerError)
-			}
-		}()
-		e.Handler.ServeHTTP(respWriter, req)
-	}()
+	// Wait for a worker; under overload the request is shed before the handler sees it.
+	// 워커를 기다립니다. 과부하 상태에서는 핸들러가 보기 전에 요청을 거절합니다.
+	outcome, guard := outcomeDone, (*timeoutBody)(nil)
+	switch {
+	case !accepted:
+		// The handler does not run for a refused request. // 거부된 요청에 대해서는 핸들러를 실행하지 않습니다.
+	case e.pool != nil && !e.pool.acquire(req.Context(), e.metrics):
+		e.pool.shedResponse(respWriter)
+	case e.requestTimeout > 0:
+		outcome, guard = e.serveTimed(ctx, respWriter, req)
+	case e.runHandler(ctx, respWriter, respWriter, req):
+		outcome = outcomeAbort
+	}
+
+	// The handler panicked or overran after sending part of its response, so the response cannot be
+	// completed; dropping the connection tells the client it is truncated.
+	// 핸들러가 응답 일부를 보낸 뒤 패닉하거나 시간을 넘겨 응답을 완성할 수 없습니다. 연결을 끊어 클라이언트에게
+	// 응답이 잘렸음을 알립니다.
+	if outcome == outcomeAbort {
+		_ = ctx.Conn().Close()
+		if guard != nil {
+			guard.wait()
+		}
+		e.logAccess(req, respWriter, start)
+		return false, errResponseAborted
+	}
+
+	// Tell the client not to reuse a connection that is being drained or closes after this request.
+	// 드레이닝 중이거나 이 요청 후 닫히는 연결은 재사용하지 않도록 클라이언트에 알립니다.
+	if c := appcontext.ConnFromContext(ctx.Req()); req.Close || c != nil && c.Draining() {
+		respWriter.Header().Set("Connection", "close")
+	}
 
-	if cancel != nil {
-		cancel()
+	// A body that stopped arriving leaves the connection unusable; answer 408 if nothing was sent yet.
+	// 도착이 멈춘 바디는 연결을 쓸 수 없게 만듭니다. 아직 아무것도 보내지 않았다면 408로 응답합니다.
+	bodyTimedOut := outcome != outcomeClose && timed != nil && timed.timedOut
+	if bodyTimedOut {
+		if respWriter.Reset() {
+			respWriter.WriteHeader(http.StatusRequestTimeout)
+			respWriter.Header().Set("Content-Length", "0")
+		}
+		respWriter.Header().Set("Connection", "close")
 	}
 
-	err = respWriter.EndResponse()
+	// A body the client was never asked for cannot be told apart from the next request, and one that
+	// is too large is not worth reading; either way the connection ends with this response.
+	// 클라이언트에게 요청하지 않은 바디는 다음 요청과 구별할 수 없고, 너무 큰 바디는 읽을 가치가 없습니다.
+	// 어느 경우든 연결은 이 응답으로 끝납니다.
+	var unreadErr error
+	if e.bodyNotRequested(respWriter, accepted) {
+		unreadErr = errBodyNotRequested
+	} else if outcome != outcomeClose && e.bodyTooLarge(respWriter, respWriter, req, sized) {
+		unreadErr = errBodyTooLarge
+	}
+	if unreadErr != nil {
+		respWriter.Header().Set("Connection", "close")
+	}
+
+	// The connection's writer is flushed by ServeConn, so responses to pipelined requests go out together.
+	// 연결의 Writer는 ServeConn이 플러시하므로, 파이프라인된 요청의 응답들이 함께 전송됩니다.
+	err := respWriter.Finish()
 	if err != nil {
-		return nil, false, err
+		// Usually the peer went away, so this is only worth seeing when debugging.
+		// 대개 피어가 떠난 경우이므로 디버깅할 때만 볼 가치가 있습니다.
+		e.connLogger(ctx).Debug("failed to write response", "method", req.Method, "path", req.URL.Path, "error", err)
+	}
+	// The overrunning handler is still reading the body. Closing the connection makes that read return,
+	// and only then may the body be looked at; the connection could not be reused anyway.
+	// 시간을 넘긴 핸들러가 아직 바디를 읽고 있습니다. 연결을 닫으면 그 읽기가 반환되며, 그 뒤에야 바디를
+	// 살펴볼 수 있습니다. 어차피 연결은 재사용할 수 없습니다.
+	if outcome == outcomeClose {
+		_ = ctx.Conn().Writer().Flush()
+		_ = ctx.Conn().Close()
+		guard.wait()
 	}
+	if e.metrics != nil && !respWriter.Hijacked() {
+		var in int64
+		if body != nil {
+			in = body.n
+		}
+		e.metrics.ObserveRequest(respWriter.Status(), time.Since(start), in, respWriter.Written())
+	}
+	e.logAccess(req, respWriter, start)
+	if outcome == outcomeClose {
+		return false, errHandlerTimeout
+	}
+	if bodyTimedOut && !respWriter.Hijacked() {
+		_ = ctx.Conn().Writer().Flush()
+		_ = ctx.Conn().Close()
+		return false, errBodyTimeout
+	}
+	if unreadErr != nil && !respWriter.Hijacked() {
+		_ = ctx.Conn().Writer().Flush()
+		_ = ctx.Conn().Close()
+		return false, unreadErr
+	}
+	// The client waits for this response before sending the body that ServeConn is about to drain.
+	// 클라이언트는 ServeConn이 비우려는 바디를 보내기 전에 이 응답을 기다립니다.
+	if respWriter.ContinueWithheld() && !respWriter.Hijacked() {
+		_ = ctx.Conn().Writer().Flush()
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return respWriter.Hijacked(), nil
+}
+
+// runHandler invokes the middleware chain and handler, recovering from panics, and frees the
+// execution pool worker when they return. It reports whether the response must be aborted.
+// runHandler는 미들웨어 체인과 핸들러를 호출하고 패닉에서 복구하며, 반환되면 실행 풀 워커를 반납합니다.
+// 응답을 중단해야 하는지 보고합니다.
+func (e *Engine) runHandler(ctx *appcontext.RequestContext, w http.ResponseWriter, state responseState, req *http.Request) (aborted bool) {
+	if e.pool != nil {
+		defer e.pool.release()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			aborted = e.handlePanic(ctx, w, state, req, r)
+		}
+	}()
+	if e.chain == nil {
+		e.Handler.ServeHTTP(w, req)
+		return false
+	}
+	e.chain(w, req, &Context{engine: e, reqCtx: ctx, conn: appcontext.ConnFromContext(ctx.Req()), resp: state})
+	return false
+}
+
+// countingBody counts the request body bytes read by the handler.
+// countingBody는 핸들러가 읽은 요청 바디 바이트 수를 셉니다.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
 
-	return req, respWriter.Hijacked(), nil
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// serveStream runs the handler for one HTTP/2 stream, applying the execution pool and the request timeout.
+// serveStream은 실행 풀과 요청 타임아웃을 적용하여 하나의 HTTP/2 스트림에 대한 핸들러를 실행합니다.
+func (e *Engine) serveStream(w http.ResponseWriter, req *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.reportStreamPanic(w, req, r)
+			panic(r)
+		}
+	}()
+	var start time.Time
+	var rec *streamRecorder
+	if e.chain != nil || e.accessLog != nil {
+		start = time.Now()
+		rec = &streamRecorder{ResponseWriter: w}
+		w = rec
+	}
+	if e.pool != nil {
+		if !e.pool.acquire(req.Context(), e.metrics) {
+			e.pool.shedResponse(w)
+			e.logStream(req, rec, start)
+			return
+		}
+		defer e.pool.release()
+	}
+	if e.requestTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(req.Context(), e.requestTimeout)
+		defer cancel()
+		req = req.WithContext(timeoutCtx)
+	}
+	if e.chain == nil {
+		e.Handler.ServeHTTP(w, req)
+	} else {
+		e.chain(w, req, &Context{engine: e, conn: appcontext.ConnFromContext(req.Context()), resp: rec})
+	}
+	e.logStream(req, rec, start)
 }
\ No newline at end of file
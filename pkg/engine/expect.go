@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/adaptor"
+)
+
+// errBodyNotRequested ends a connection whose client is still holding back a body it was never asked for.
+// errBodyNotRequested는 요청받지 않은 바디를 클라이언트가 아직 보류하고 있는 연결을 종료합니다.
+var errBodyNotRequested = errors.New("engine: request body was never requested from the client")
+
+// ContinuePolicy decides how requests sent with "Expect: 100-continue" are served.
+// ContinuePolicy는 "Expect: 100-continue"로 보낸 요청을 어떻게 처리할지 정합니다.
+type ContinuePolicy int
+
+const (
+	// ContinueClose sends "100 Continue" when the handler first reads the body. When the handler responds
+	// without reading it, the client may never send the body, so the connection is closed after the
+	// response, as net/http does. This is the default.
+	// ContinueClose는 핸들러가 바디를 처음 읽을 때 "100 Continue"를 보냅니다. 핸들러가 바디를 읽지 않고 응답하면
+	// 클라이언트가 바디를 보내지 않을 수 있으므로, net/http와 같이 응답 후 연결을 닫습니다. 기본값입니다.
+	ContinueClose ContinuePolicy = iota
+	// ContinueDrain is ContinueClose, except that an unread body is drained to keep the connection, for
+	// clients that send the body anyway. Bound the wait with WithBodyReadTimeout.
+	// ContinueDrain은 ContinueClose와 같지만, 어차피 바디를 보내는 클라이언트를 위해 읽지 않은 바디를 비우고 연결을
+	// 유지합니다. 대기 시간은 WithBodyReadTimeout으로 제한합니다.
+	ContinueDrain
+	// ContinueReject answers every "Expect: 100-continue" with 417 Expectation Failed without running the
+	// handler, and closes the connection.
+	// ContinueReject는 모든 "Expect: 100-continue"에 핸들러를 실행하지 않고 417 Expectation Failed로 응답하며
+	// 연결을 닫습니다.
+	ContinueReject
+)
+
+// WithExpectContinue sets how HTTP/1.1 requests sent with "Expect: 100-continue" are served. Any other
+// expectation is always answered with 417 Expectation Failed.
+// WithExpectContinue는 "Expect: 100-continue"로 보낸 HTTP/1.1 요청을 처리하는 방식을 설정합니다.
+// 그 밖의 기대값에는 항상 417 Expectation Failed로 응답합니다.
+func WithExpectContinue(p ContinuePolicy) Option {
+	return func(e *Engine) {
+		e.continuePolicy = p
+	}
+}
+
+// checkExpect prepares the response for the request's Expect header. It reports false when the
+// expectation is refused and the response is a 417.
+// checkExpect는 요청의 Expect 헤더에 맞게 응답을 준비합니다. 기대를 거부하여 응답이 417이면 false를 보고합니다.
+func (e *Engine) checkExpect(w *adaptor.ResponseWriter, req *http.Request) bool {
+	expect := req.Header.Get("Expect")
+	if expect == "" {
+		return true
+	}
+	// HTTP/1.0 clients do not know the interim response and send the body anyway.
+	// HTTP/1.0 클라이언트는 중간 응답을 모르며 어차피 바디를 보냅니다.
+	if strings.EqualFold(expect, "100-continue") && e.continuePolicy != ContinueReject {
+		if req.ProtoAtLeast(1, 1) {
+			w.ExpectContinue()
+		}
+		return true
+	}
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusExpectationFailed)
+	return false
+}
+
+// bodyNotRequested reports whether the client may still be holding back the body because it was never
+// sent "100 Continue", so the connection cannot be reused.
+// bodyNotRequested는 "100 Continue"를 받지 못한 클라이언트가 아직 바디를 보류하고 있을 수 있어 연결을 재사용할 수
+// 없는지 보고합니다.
+func (e *Engine) bodyNotRequested(w *adaptor.ResponseWriter, accepted bool) bool {
+	if w.Hijacked() {
+		return false
+	}
+	return !accepted || e.continuePolicy == ContinueClose && w.ContinueWithheld()
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// errBodyTooLarge ends a connection whose request body is over the limit or too large to drain.
+// errBodyTooLarge는 요청 바디가 한도를 넘었거나 비우기에 너무 큰 연결을 종료합니다.
+var errBodyTooLarge = errors.New("engine: request body too large")
+
+// defaultMaxDrainBytes matches how much unread body net/http drains to keep a connection.
+// defaultMaxDrainBytes는 net/http가 연결을 유지하기 위해 비우는 읽지 않은 바디의 양과 같습니다.
+const defaultMaxDrainBytes = 256 << 10
+
+// WithMaxHeaderBytes limits the size of a request's line and headers. A larger request is answered
+// with 431 Request Header Fields Too Large and the connection is closed. As in net/http the limit is
+// approximate, and the default is http.DefaultMaxHeaderBytes; n <= 0 removes it.
+// WithMaxHeaderBytes는 요청 줄과 헤더의 크기를 제한합니다. 더 큰 요청에는 431 Request Header Fields Too Large로
+// 응답하고 연결을 닫습니다. net/http와 같이 한도는 근사치이며, 기본값은 http.DefaultMaxHeaderBytes입니다.
+// n <= 0이면 한도를 없앱니다.
+func WithMaxHeaderBytes(n int) Option {
+	return func(e *Engine) {
+		e.maxHeaderBytes = n
+	}
+}
+
+// WithMaxBodyBytes limits request bodies to n bytes, like http.MaxBytesReader. A request whose
+// Content-Length is larger gets 413 Content Too Large without running the handler. Otherwise reads
+// past the limit fail with *http.MaxBytesError, and the client gets a 413 unless the handler set a
+// status. Either way the connection is closed after the response.
+// WithMaxBodyBytes는 http.MaxBytesReader처럼 요청 바디를 n바이트로 제한합니다. Content-Length가 더 큰 요청은
+// 핸들러를 실행하지 않고 413 Content Too Large를 받습니다. 그 밖에는 한도를 넘는 읽기가 *http.MaxBytesError로
+// 실패하며, 핸들러가 상태를 설정하지 않았다면 클라이언트는 413을 받습니다. 어느 경우든 응답 후 연결을 닫습니다.
+func WithMaxBodyBytes(n int64) Option {
+	return func(e *Engine) {
+		e.maxBodyBytes = n
+	}
+}
+
+// WithMaxDrainBytes sets how much of a body the handler left unread is read and discarded to keep the
+// connection. When more remains, the connection is closed after the response instead. The default is
+// 256 KiB, as in net/http; a negative n drains bodies of any size.
+// WithMaxDrainBytes는 연결을 유지하기 위해 핸들러가 읽지 않은 바디를 얼마나 읽어서 버릴지 설정합니다.
+// 그보다 많이 남으면 대신 응답 후 연결을 닫습니다. 기본값은 net/http와 같은 256 KiB이며, n이 음수이면
+// 크기에 관계없이 바디를 비웁니다.
+func WithMaxDrainBytes(n int64) Option {
+	return func(e *Engine) {
+		e.maxDrainBytes = n
+	}
+}
+
+// checkBodySize answers 413 for a request that announces a body over the limit. It reports false when
+// the request is refused.
+// checkBodySize는 한도를 넘는 바디를 알린 요청에 413으로 응답합니다. 요청을 거부하면 false를 보고합니다.
+func (e *Engine) checkBodySize(w http.ResponseWriter, req *http.Request) bool {
+	if e.maxBodyBytes <= 0 || req.ContentLength <= e.maxBodyBytes {
+		return true
+	}
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	return false
+}
+
+// limitBody wraps the request body to enforce the body limit and see how much of it is read.
+// limitBody는 바디 한도를 적용하고 읽은 양을 알 수 있도록 요청 바디를 감쌉니다.
+func (e *Engine) limitBody(req *http.Request) *sizedBody {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if e.maxBodyBytes <= 0 && e.maxDrainBytes < 0 {
+		return nil
+	}
+	b := &sizedBody{ReadCloser: req.Body, limit: -1}
+	if e.maxBodyBytes > 0 {
+		b.limit = e.maxBodyBytes
+	}
+	req.Body = b
+	return b
+}
+
+// bodyTooLarge reports whether the rest of the body should not be read: it went over the limit, or
+// more of it than the drain limit is known to remain. A handler that set no status then gets a 413.
+// bodyTooLarge는 바디의 나머지를 읽지 말아야 하는지 보고합니다: 한도를 넘었거나, 비우기 한도보다 많이 남은 것이
+// 확실한 경우입니다. 이때 상태를 설정하지 않은 핸들러는 413을 받습니다.
+func (e *Engine) bodyTooLarge(w http.ResponseWriter, state responseState, req *http.Request, b *sizedBody) bool {
+	if b == nil {
+		return false
+	}
+	if b.hit {
+		if state.Status() == 0 {
+			w.Header().Set("Content-Length", "0")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+		return true
+	}
+	return e.maxDrainBytes >= 0 && req.ContentLength-b.n > e.maxDrainBytes
+}
+
+// drainBody discards what is left of a request body, reporting false when more remains than may be drained.
+// drainBody는 요청 바디의 남은 부분을 버리며, 비울 수 있는 양보다 많이 남았다면 false를 보고합니다.
+func (e *Engine) drainBody(body io.ReadCloser) bool {
+	defer body.Close()
+	if e.maxDrainBytes < 0 {
+		_, _ = io.Copy(io.Discard, body)
+		return true
+	}
+	n, _ := io.CopyN(io.Discard, body, e.maxDrainBytes+1)
+	return n <= e.maxDrainBytes
+}
+
+// sizedBody counts the body bytes read and fails reads past its limit like http.MaxBytesReader.
+// sizedBody는 읽은 바디 바이트 수를 세고, http.MaxBytesReader처럼 한도를 넘는 읽기를 실패시킵니다.
+type sizedBody struct {
+	io.ReadCloser
+	n     int64
+	limit int64 // Negative without a limit. // 한도가 없으면 음수입니다.
+	hit   bool
+}
+
+func (b *sizedBody) Read(p []byte) (int, error) {
+	if b.hit {
+		return 0, &http.MaxBytesError{Limit: b.limit}
+	}
+	// Read one byte past the limit to tell a body that ends there from a longer one.
+	// 한도에서 끝나는 바디와 더 긴 바디를 구별하기 위해 한도보다 한 바이트 더 읽습니다.
+	if b.limit >= 0 && int64(len(p)) > b.limit-b.n+1 {
+		p = p[:b.limit-b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if b.limit >= 0 && b.n > b.limit {
+		n -= int(b.n - b.limit)
+		b.n = b.limit
+		b.hit = true
+		return n, &http.MaxBytesError{Limit: b.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+)
+
+// HandlerFunc is a step of the middleware chain. Besides the standard arguments it receives c,
+// the engine's view of the request.
+// HandlerFunc는 미들웨어 체인의 한 단계입니다. 표준 인자 외에 엔진 관점의 요청 정보인 c를 받습니다.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request, c *Context)
+
+// Middleware wraps the next step of the chain. // Middleware는 체인의 다음 단계를 감쌉니다.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Use appends middleware to the Engine's chain. The first middleware added runs outermost, and the
+// Engine's Handler runs last. Use must be called before the Engine starts serving.
+// Use는 Engine의 체인에 미들웨어를 추가합니다. 먼저 추가한 미들웨어가 가장 바깥에서 실행되며 Engine의
+// Handler가 마지막에 실행됩니다. Use는 Engine이 요청을 처리하기 시작하기 전에 호출해야 합니다.
+func (e *Engine) Use(mw ...Middleware) {
+	e.middleware = append(e.middleware, mw...)
+	chain := HandlerFunc(func(w http.ResponseWriter, r *http.Request, _ *Context) {
+		e.Handler.ServeHTTP(w, r)
+	})
+	for i := len(e.middleware) - 1; i >= 0; i-- {
+		chain = e.middleware[i](chain)
+	}
+	e.chain = chain
+}
+
+// responseState is what Context reports about the response being written.
+// responseState는 Context가 작성 중인 응답에 대해 보고하는 정보입니다.
+type responseState interface {
+	Status() int
+	Written() int64
+	Committed() bool
+	Hijacked() bool
+	Reset() bool
+}
+
+// Context exposes engine internals to middleware for the duration of one request.
+// Context는 하나의 요청 동안 미들웨어에 엔진 내부 정보를 노출합니다.
+type Context struct {
+	engine *Engine
+	reqCtx *appcontext.RequestContext
+	conn   *appcontext.Conn
+	resp   responseState
+}
+
+// RequestContext returns the HTTP/1.x request context, or nil for an HTTP/2 stream.
+// RequestContext는 HTTP/1.x 요청 컨텍스트를 반환하며, HTTP/2 스트림이면 nil을 반환합니다.
+func (c *Context) RequestContext() *appcontext.RequestContext {
+	return c.reqCtx
+}
+
+// Conn returns the server connection carrying the request, or nil when the Engine is used without a Server.
+// Conn은 요청을 전달한 서버 연결을 반환하며, Server 없이 Engine을 사용하면 nil을 반환합니다.
+func (c *Context) Conn() *appcontext.Conn {
+	return c.conn
+}
+
+// HTTP2 reports whether the request arrived on an HTTP/2 stream.
+// HTTP2는 요청이 HTTP/2 스트림으로 도착했는지 보고합니다.
+func (c *Context) HTTP2() bool {
+	return c.reqCtx == nil
+}
+
+// Status returns the response status set so far, or 0. // Status는 지금까지 설정된 응답 상태를 반환하며, 없으면 0입니다.
+func (c *Context) Status() int {
+	return c.resp.Status()
+}
+
+// Written returns the response body bytes written by the handler.
+// Written은 핸들러가 쓴 응답 바디 바이트 수를 반환합니다.
+func (c *Context) Written() int64 {
+	return c.resp.Written()
+}
+
+// Committed reports whether the response headers have been sent or the connection hijacked,
+// after which headers and status can no longer change.
+// Committed는 응답 헤더가 전송되었거나 연결이 하이재킹되어 더 이상 헤더와 상태를 바꿀 수 없는지 보고합니다.
+func (c *Context) Committed() bool {
+	return c.resp.Committed()
+}
+
+// Hijacked reports whether the handler took over the connection.
+// Hijacked는 핸들러가 연결을 넘겨받았는지 보고합니다.
+func (c *Context) Hijacked() bool {
+	return c.resp.Hijacked()
+}
+
+// Streamed reports whether the handler has already sent part of the response by flushing it,
+// rather than leaving the engine to send the buffered response when it returns.
+// Streamed는 핸들러가 반환될 때 엔진이 버퍼링된 응답을 보내도록 두지 않고, 플러시하여 응답의 일부를
+// 이미 보냈는지 보고합니다.
+func (c *Context) Streamed() bool {
+	return c.resp.Committed() && !c.resp.Hijacked()
+}
+
+// Reset discards the headers, status and body written so far, reporting false when the response
+// is already committed.
+// Reset은 지금까지 작성된 헤더, 상태, 바디를 버리며, 응답이 이미 커밋되었다면 false를 보고합니다.
+func (c *Context) Reset() bool {
+	return c.resp.Reset()
+}
+
+// Logger returns the Engine's logger with the connection's details attached.
+// Logger는 연결 정보가 추가된 Engine의 로거를 반환합니다.
+func (c *Context) Logger() *slog.Logger {
+	if c.reqCtx != nil {
+		return c.engine.connLogger(c.reqCtx)
+	}
+	l := c.engine.log()
+	if c.conn != nil {
+		l = l.With("remote_addr", c.conn.RemoteAddr().String(), "conn_id", c.conn.ID())
+	}
+	return l
+}
+
+// streamRecorder tracks an HTTP/2 response for Context. // streamRecorder는 Context를 위해 HTTP/2 응답을 추적합니다.
+type streamRecorder struct {
+	http.ResponseWriter
+	status    int
+	written   int64
+	committed bool
+}
+
+func (s *streamRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *streamRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(p)
+	s.written += int64(n)
+	return n, err
+}
+
+func (s *streamRecorder) Flush() {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	s.committed = true
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the stream's writer.
+// Unwrap은 http.ResponseController가 스트림의 Writer에 접근할 수 있게 합니다.
+func (s *streamRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *streamRecorder) Status() int     { return s.status }
+func (s *streamRecorder) Written() int64  { return s.written }
+func (s *streamRecorder) Committed() bool { return s.committed }
+func (s *streamRecorder) Hijacked() bool  { return false }
+
+// Reset cannot discard what the stream has buffered, so it only succeeds before anything was written.
+// Reset은 스트림이 버퍼링한 내용을 버릴 수 없으므로, 아무것도 쓰지 않았을 때만 성공합니다.
+func (s *streamRecorder) Reset() bool {
+	if s.committed || s.status != 0 {
+		return false
+	}
+	clear(s.Header())
+	return true
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+)
+
+// errResponseAborted ends a connection whose response could not be completed.
+// errResponseAborted는 응답을 완성할 수 없는 연결을 종료합니다.
+var errResponseAborted = errors.New("engine: response aborted")
+
+// PanicHandler answers a request whose handler panicked with recovered, given the goroutine's stack.
+// PanicHandler는 핸들러가 recovered로 패닉한 요청에 응답하며, 고루틴의 스택을 함께 받습니다.
+type PanicHandler func(w http.ResponseWriter, r *http.Request, recovered any, stack []byte)
+
+// WithPanicHandler replaces the default panic handling, which logs the panic with its stack and
+// answers 500. The response is reset before h runs, and a 500 is sent if h writes nothing. When part
+// of the response was already sent the connection is aborted after h returns, so h can only report.
+// Panics with http.ErrAbortHandler skip h and abort the connection quietly, as with net/http.
+// WithPanicHandler는 패닉을 스택과 함께 기록하고 500으로 응답하는 기본 패닉 처리를 대체합니다.
+// h가 실행되기 전에 응답은 초기화되며, h가 아무것도 쓰지 않으면 500을 보냅니다. 응답 일부가 이미 전송되었다면
+// h가 반환된 뒤 연결을 중단하므로 h는 보고만 할 수 있습니다. http.ErrAbortHandler로 인한 패닉은 net/http와
+// 같이 h를 건너뛰고 조용히 연결을 중단합니다.
+func WithPanicHandler(h PanicHandler) Option {
+	return func(e *Engine) {
+		e.panicHandler = h
+	}
+}
+
+// handlePanic deals with a panic recovered from the handler, reporting whether the connection must be aborted.
+// handlePanic은 핸들러에서 복구한 패닉을 처리하며, 연결을 중단해야 하는지 보고합니다.
+func (e *Engine) handlePanic(ctx *appcontext.RequestContext, w http.ResponseWriter, state responseState, req *http.Request, recovered any) bool {
+	if recovered == http.ErrAbortHandler {
+		return !state.Hijacked()
+	}
+	stack := debug.Stack()
+	if e.metrics != nil {
+		e.metrics.PanicRecovered()
+	}
+
+	replaced := state.Reset()
+	if e.panicHandler != nil {
+		e.panicHandler(w, req, recovered, stack)
+	} else {
+		e.connLogger(ctx).Error("panic in handler", "method", req.Method, "path", req.URL.Path, "panic", recovered, "stack", string(stack))
+	}
+	if !replaced {
+		return !state.Hijacked()
+	}
+	if state.Status() == 0 {
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	return false
+}
+
+// reportStreamPanic reports a panic from an HTTP/2 handler; the HTTP/2 server then resets the stream or
+// answers 500, so a PanicHandler's writes are discarded.
+// reportStreamPanic은 HTTP/2 핸들러의 패닉을 보고합니다. 이후 HTTP/2 서버가 스트림을 리셋하거나 500으로
+// 응답하므로 PanicHandler의 쓰기는 버려집니다.
+func (e *Engine) reportStreamPanic(w http.ResponseWriter, req *http.Request, recovered any) {
+	if recovered == http.ErrAbortHandler {
+		return
+	}
+	stack := debug.Stack()
+	if e.metrics != nil {
+		e.metrics.PanicRecovered()
+	}
+	if e.panicHandler != nil {
+		e.panicHandler(w, req, recovered, stack)
+		return
+	}
+	e.log().Error("panic in handler", "remote_addr", req.RemoteAddr, "method", req.Method, "path", req.URL.Path, "panic", recovered, "stack", string(stack))
+}
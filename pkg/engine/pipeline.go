@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// maxPendingOutput bounds the responses held back for pipelined requests before they are flushed anyway.
+// maxPendingOutput은 파이프라인된 요청을 위해 보류하는 응답의 크기를 제한하며, 넘으면 바로 플러시합니다.
+const maxPendingOutput = 64 << 10
+
+// WithPipelineConcurrency runs up to n pipelined requests of a connection at once. Responses are still
+// written in request order. Only requests without a body or an Upgrade header take part; any other request
+// waits for the ones before it. Handlers for those requests must not hijack the connection.
+// A value of 1 or less, the default, handles pipelined requests one at a time.
+// WithPipelineConcurrency는 한 연결에서 파이프라인된 요청을 최대 n개까지 동시에 실행합니다. 응답은 여전히
+// 요청 순서대로 쓰입니다. 바디와 Upgrade 헤더가 없는 요청만 참여하며, 그 밖의 요청은 앞선 요청들을 기다립니다.
+// 이 요청들의 핸들러는 연결을 하이재킹하면 안 됩니다. 기본값인 1 이하에서는 파이프라인된 요청을 하나씩 처리합니다.
+func WithPipelineConcurrency(n int) Option {
+	return func(e *Engine) {
+		e.pipelineConcurrency = n
+	}
+}
+
+// flushPending sends the responses held back for pipelined requests once no further request is
+// buffered, or earlier when they grow past maxPendingOutput.
+// flushPending은 더 이상 버퍼링된 요청이 없으면, 또는 보류한 응답이 maxPendingOutput을 넘으면 그보다 먼저,
+// 파이프라인된 요청을 위해 보류한 응답을 보냅니다.
+func flushPending(conn netpoll.Connection, reader *bufio.Reader) error {
+	w := conn.Writer()
+	if w.MallocLen() == 0 {
+		return nil
+	}
+	if requestBuffered(reader) && w.MallocLen() < maxPendingOutput {
+		return nil
+	}
+	return w.Flush()
+}
+
+// requestBuffered reports whether the reader holds a complete request head, so reading the next request
+// will not wait on the peer. A partial request does not count: its client may be waiting for our answers.
+// requestBuffered는 reader에 완전한 요청 헤더가 있어 다음 요청을 읽을 때 피어를 기다리지 않는지 보고합니다.
+// 일부만 도착한 요청은 포함하지 않습니다. 그 클라이언트는 응답을 기다리고 있을 수 있습니다.
+func requestBuffered(reader *bufio.Reader) bool {
+	b, _ := reader.Peek(reader.Buffered())
+	return bytes.Contains(b, []byte("\n\r\n")) || bytes.Contains(b, []byte("\n\n"))
+}
+
+// concurrentSafe reports whether req can run alongside other pipelined requests: reading the next
+// request must not race with its body, and it must not take over the connection.
+// concurrentSafe는 req가 다른 파이프라인 요청과 함께 실행될 수 있는지 보고합니다. 다음 요청을 읽는 것이
+// 바디 읽기와 경합하면 안 되며, 연결을 넘겨받아서도 안 됩니다.
+func concurrentSafe(req *http.Request) bool {
+	return (req.Body == nil || req.Body == http.NoBody) && req.Header.Get("Upgrade") == ""
+}
+
+// serveBatch handles first together with the pipelined requests buffered behind it, up to the
+// configured concurrency, and writes their responses in order. The batched requests are counted in
+// limits. It returns the last request served, and the next request if one was read that cannot join the batch.
+// serveBatch는 first와 그 뒤에 버퍼링된 파이프라인 요청들을 설정된 동시성까지 함께 처리하고 응답을 순서대로
+// 씁니다. 배치된 요청은 limits에 반영됩니다. 마지막으로 처리한 요청과, 배치에 참여할 수 없는 요청을 읽었다면
+// 그 다음 요청을 반환합니다.
+func (e *Engine) serveBatch(ctx *appcontext.RequestContext, first *http.Request, limits *connLimits) (last, next *http.Request, err error) {
+	batch := []*http.Request{first}
+	var readErr error
+	for len(batch) < e.pipelineConcurrency && !batch[len(batch)-1].Close && requestBuffered(ctx.Reader()) {
+		req, err := e.readRequest(ctx)
+		if err != nil {
+			readErr = err
+			break
+		}
+		if !concurrentSafe(req) {
+			next = req
+			break
+		}
+		limits.admit(req)
+		batch = append(batch, req)
+	}
+
+	// Each handler writes into its own buffer so the responses can be put back in order.
+	// 응답을 순서대로 되돌릴 수 있도록 각 핸들러는 자신의 버퍼에 씁니다.
+	conns := make([]*bufferedConn, len(batch))
+	var wg sync.WaitGroup
+	for i, req := range batch {
+		bc := newBufferedConn(ctx.Conn())
+		conns[i] = bc
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reqCtx := appcontext.NewRequestContext(bc, ctx.Req())
+			defer reqCtx.Release()
+			_, _ = e.handleRequest(reqCtx, req)
+		}()
+	}
+	wg.Wait()
+
+	w := ctx.Conn().Writer()
+	for _, bc := range conns {
+		if err := bc.w.Flush(); err != nil {
+			return nil, nil, err
+		}
+		if _, err := w.WriteBinary(bc.buf.Bytes()); err != nil {
+			return nil, nil, err
+		}
+	}
+	if readErr != nil {
+		return nil, nil, e.failRequest(ctx, readErr)
+	}
+	return batch[len(batch)-1], next, nil
+}
+
+// bufferedConn collects a concurrently handled response until its turn to be written.
+// bufferedConn은 동시에 처리된 응답을 쓰일 차례가 될 때까지 모읍니다.
+type bufferedConn struct {
+	netpoll.Connection
+	buf bytes.Buffer
+	w   netpoll.Writer
+}
+
+func newBufferedConn(conn netpoll.Connection) *bufferedConn {
+	bc := &bufferedConn{Connection: conn}
+	bc.w = netpoll.NewWriter(&bc.buf)
+	return bc
+}
+
+func (c *bufferedConn) Writer() netpoll.Writer {
+	return c.w
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/metrics"
+)
+
+// WithExecutionPool bounds the number of handlers running at once to workers. Up to queue requests
+// wait for a free worker, each for at most maxWait (no limit when zero). A request that finds the queue
+// full, or is still waiting after maxWait, gets a 503 with Retry-After instead of running the handler.
+// WithExecutionPool은 동시에 실행되는 핸들러 수를 workers개로 제한합니다. 최대 queue개의 요청이 빈 워커를
+// 기다리며, 각각 최대 maxWait 동안 기다립니다(0이면 제한 없음). 대기열이 가득 찼거나 maxWait 이후에도 기다리고
+// 있는 요청은 핸들러를 실행하는 대신 Retry-After가 포함된 503을 받습니다.
+func WithExecutionPool(workers, queue int, maxWait time.Duration) Option {
+	return func(e *Engine) {
+		if workers <= 0 {
+			e.pool = nil
+			return
+		}
+		e.pool = &executionPool{
+			slots:   make(chan struct{}, workers),
+			queue:   int64(max(queue, 0)),
+			maxWait: maxWait,
+		}
+	}
+}
+
+// ExecutionStats is a snapshot of the execution pool. // ExecutionStats는 실행 풀의 스냅샷입니다.
+type ExecutionStats struct {
+	Workers int    // Handlers allowed to run at once. // 동시에 실행할 수 있는 핸들러 수입니다.
+	Active  int    // Handlers running now. // 지금 실행 중인 핸들러 수입니다.
+	Queued  int    // Requests waiting for a worker. // 워커를 기다리는 요청 수입니다.
+	Shed    uint64 // Requests answered with 503 so far. // 지금까지 503으로 응답한 요청 수입니다.
+}
+
+// ExecutionStats reports the state of the execution pool; it is zero without WithExecutionPool.
+// ExecutionStats는 실행 풀의 상태를 보고하며, WithExecutionPool이 없으면 0입니다.
+func (e *Engine) ExecutionStats() ExecutionStats {
+	if e.pool == nil {
+		return ExecutionStats{}
+	}
+	return ExecutionStats{
+		Workers: cap(e.pool.slots),
+		Active:  len(e.pool.slots),
+		Queued:  int(e.pool.queued.Load()),
+		Shed:    e.pool.shed.Load(),
+	}
+}
+
+// executionPool admits handlers through a semaphore with a bounded wait queue.
+// executionPool은 대기열 크기가 제한된 세마포어로 핸들러 실행을 허가합니다.
+type executionPool struct {
+	slots   chan struct{}
+	queue   int64
+	maxWait time.Duration
+	queued  atomic.Int64
+	shed    atomic.Uint64
+}
+
+// acquire waits for a worker and reports whether the handler may run; release must follow a true result.
+// acquire는 워커를 기다리고 핸들러를 실행해도 되는지 보고합니다. true를 받았다면 반드시 release를 호출해야 합니다.
+func (p *executionPool) acquire(ctx context.Context, m *metrics.Metrics) bool {
+	select {
+	case p.slots <- struct{}{}:
+		if m != nil {
+			m.ObserveQueueWait(0)
+		}
+		return true
+	default:
+	}
+
+	if p.queued.Add(1) > p.queue {
+		p.queued.Add(-1)
+		p.reject(m)
+		return false
+	}
+	if m != nil {
+		m.QueueEntered()
+	}
+	start := time.Now()
+	ok := p.wait(ctx)
+	p.queued.Add(-1)
+	if m != nil {
+		m.QueueLeft()
+		m.ObserveQueueWait(time.Since(start))
+	}
+	if !ok {
+		p.reject(m)
+	}
+	return ok
+}
+
+func (p *executionPool) wait(ctx context.Context) bool {
+	var expired <-chan time.Time
+	if p.maxWait > 0 {
+		t := time.NewTimer(p.maxWait)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-expired:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+func (p *executionPool) release() {
+	<-p.slots
+}
+
+func (p *executionPool) reject(m *metrics.Metrics) {
+	p.shed.Add(1)
+	if m != nil {
+		m.RequestShed()
+	}
+}
+
+// retryAfter suggests how long a shed client should back off: the queue wait, and at least a second.
+// retryAfter는 거절된 클라이언트가 물러나 있을 시간을 제안합니다. 대기열 대기 시간이며 최소 1초입니다.
+func (p *executionPool) retryAfter() string {
+	return strconv.Itoa(max(1, int(math.Ceil(p.maxWait.Seconds()))))
+}
+
+// shedResponse answers a request that was not admitted. // shedResponse는 허가되지 않은 요청에 응답합니다.
+func (p *executionPool) shedResponse(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", p.retryAfter())
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/adaptor"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/h2"
+)
+
+// RequestErrorHandler writes the response to a request that could not be read or was refused before
+// reaching a handler, such as a malformed request line or headers that are too large.
+// RequestErrorHandler는 잘못된 요청 라인이나 너무 큰 헤더처럼 읽을 수 없었거나 핸들러에 도달하기 전에 거부된
+// 요청에 대한 응답을 씁니다.
+type RequestErrorHandler func(w http.ResponseWriter, err *adaptor.RequestError)
+
+// WithRequestErrorHandler replaces the plain-text error page sent for malformed requests. The status is
+// err.Status unless h sets another, and the connection is closed after the response. h must not hijack
+// the connection.
+// WithRequestErrorHandler는 잘못된 요청에 보내는 일반 텍스트 오류 페이지를 대체합니다. h가 다른 상태를 설정하지
+// 않으면 상태는 err.Status이며, 응답 후 연결을 닫습니다. h는 연결을 하이재킹해서는 안 됩니다.
+func WithRequestErrorHandler(h RequestErrorHandler) Option {
+	return func(e *Engine) {
+		e.requestErrorHandler = h
+	}
+}
+
+// validateRequest refuses what http.ReadRequest accepts but the engine cannot serve. The client preface
+// of cleartext HTTP/2 is left to the HTTP/2 server.
+// validateRequest는 http.ReadRequest가 받아들이지만 엔진이 처리할 수 없는 요청을 거부합니다. 평문 HTTP/2의
+// 클라이언트 프리페이스는 HTTP/2 서버에 맡깁니다.
+func (e *Engine) validateRequest(req *http.Request) error {
+	if e.h2 != nil && h2.IsPriorKnowledge(req) {
+		return nil
+	}
+	return adaptor.ValidateRequest(req)
+}
+
+// requestError tells how a failed read should be answered, or returns nil when the client is not
+// waiting for an answer.
+// requestError는 실패한 읽기에 어떻게 응답해야 하는지 알려주며, 클라이언트가 응답을 기다리지 않으면 nil을 반환합니다.
+func requestError(ctx *appcontext.RequestContext, err error) *adaptor.RequestError {
+	var reqErr *adaptor.RequestError
+	switch {
+	case isTimeout(err):
+		return &adaptor.RequestError{Status: http.StatusRequestTimeout, Reason: "request headers not received in time", Err: err}
+	case ctx.ReadLimitReached() && !ctx.LineEnded():
+		return &adaptor.RequestError{Status: http.StatusRequestURITooLong, Reason: "request line too long", Err: err}
+	case ctx.ReadLimitReached():
+		return &adaptor.RequestError{Status: http.StatusRequestHeaderFieldsTooLarge, Reason: "request headers too large", Err: err}
+	case errors.As(err, &reqErr):
+		return reqErr
+	}
+	return nil
+}
+
+// writeRequestError sends the response for reqErr through the RequestErrorHandler, or a short
+// plain-text page like net/http's, with "Connection: close".
+// writeRequestError는 reqErr에 대한 응답을 RequestErrorHandler로, 없으면 net/http와 같은 짧은 일반 텍스트
+// 페이지로 "Connection: close"와 함께 보냅니다.
+func (e *Engine) writeRequestError(ctx *appcontext.RequestContext, reqErr *adaptor.RequestError) {
+	stub := &http.Request{Method: http.MethodGet, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{}}
+	w := adaptor.NewResponseWriter(ctx, stub)
+	defer w.Release()
+
+	if e.requestErrorHandler != nil {
+		e.requestErrorHandler(w, reqErr)
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(reqErr.Status)
+		io.WriteString(w, strconv.Itoa(reqErr.Status)+" "+http.StatusText(reqErr.Status)+": "+reqErr.Reason)
+	}
+	if w.Status() == 0 {
+		w.WriteHeader(reqErr.Status)
+	}
+	w.Header().Set("Connection", "close")
+	if !w.Committed() && w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.FormatInt(w.Written(), 10))
+	}
+	_ = w.EndResponse()
+}
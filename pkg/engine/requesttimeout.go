@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/adaptor"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+)
+
+// errHandlerTimeout ends a connection whose overrunning handler is still reading the request body.
+// errHandlerTimeout은 시간을 넘긴 핸들러가 아직 요청 바디를 읽고 있는 연결을 종료합니다.
+var errHandlerTimeout = errors.New("engine: handler timed out")
+
+// defaultTimeoutBody matches http.TimeoutHandler's page. // defaultTimeoutBody는 http.TimeoutHandler의 페이지와 같습니다.
+const defaultTimeoutBody = "<html><head><title>Timeout</title></head><body><h1>Timeout</h1></body></html>"
+
+// WithTimeoutResponse sets the response sent when a handler overruns the request timeout, instead of
+// 503 Service Unavailable with a short HTML page. code is typically 503 or 504.
+// WithTimeoutResponse는 핸들러가 요청 타임아웃을 넘겼을 때 보낼 응답을 설정합니다. 기본값은 짧은 HTML
+// 페이지와 함께 보내는 503 Service Unavailable이며, code는 보통 503이나 504입니다.
+func WithTimeoutResponse(code int, body string) Option {
+	return func(e *Engine) {
+		e.timeoutCode = code
+		e.timeoutBody = body
+	}
+}
+
+// SetRequestTimeout replaces the timeout of the request being handled, counting from when the engine
+// started it; d <= 0 removes it. Middleware can use it to give routes their own timeout. It reports
+// false when the request is not under WithRequestTimeout or has already timed out.
+// SetRequestTimeout은 처리 중인 요청의 타임아웃을 엔진이 요청을 시작한 시점부터 다시 설정하며, d <= 0이면
+// 제거합니다. 미들웨어는 이를 사용해 경로별 타임아웃을 줄 수 있습니다. 요청이 WithRequestTimeout 아래에 있지
+// 않거나 이미 타임아웃되었다면 false를 보고합니다.
+func SetRequestTimeout(r *http.Request, d time.Duration) bool {
+	t, ok := r.Context().Value(requestTimerKey{}).(*requestTimer)
+	return ok && t.set(d)
+}
+
+type requestTimerKey struct{}
+
+// requestTimer is a resettable request deadline. // requestTimer는 다시 설정할 수 있는 요청 데드라인입니다.
+type requestTimer struct {
+	mu       sync.Mutex
+	start    time.Time
+	deadline time.Time // Zero without a timeout. // 타임아웃이 없으면 0입니다.
+	timer    *time.Timer
+	gen      int // Ignores a timer that fired while being replaced. // 교체 중에 만료된 타이머를 무시합니다.
+	expired  bool
+	fired    chan struct{}
+	cancel   context.CancelFunc
+}
+
+func (t *requestTimer) set(d time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.expired {
+		return false
+	}
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.gen++
+	if d <= 0 {
+		t.deadline = time.Time{}
+		return true
+	}
+	t.deadline = t.start.Add(d)
+	gen := t.gen
+	t.timer = time.AfterFunc(time.Until(t.deadline), func() { t.expire(gen) })
+	return true
+}
+
+func (t *requestTimer) expire(gen int) {
+	t.mu.Lock()
+	if t.expired || gen != t.gen {
+		t.mu.Unlock()
+		return
+	}
+	t.expired = true
+	t.mu.Unlock()
+	close(t.fired)
+	t.cancel()
+}
+
+func (t *requestTimer) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// timeoutContext is the handler's context: it reports the current deadline and DeadlineExceeded once it passes.
+// timeoutContext는 핸들러의 컨텍스트로, 현재 데드라인을 보고하며 데드라인이 지나면 DeadlineExceeded를 보고합니다.
+type timeoutContext struct {
+	context.Context
+	t *requestTimer
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	c.t.mu.Lock()
+	defer c.t.mu.Unlock()
+	if c.t.deadline.IsZero() {
+		return c.Context.Deadline()
+	}
+	return c.t.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	err := c.Context.Err()
+	if err != nil {
+		c.t.mu.Lock()
+		defer c.t.mu.Unlock()
+		if c.t.expired {
+			return context.DeadlineExceeded
+		}
+	}
+	return err
+}
+
+func (c *timeoutContext) Value(key any) any {
+	if key == (requestTimerKey{}) {
+		return c.t
+	}
+	return c.Context.Value(key)
+}
+
+// handlerOutcome is what became of the response once the handler returned or overran.
+// handlerOutcome은 핸들러가 반환되거나 시간을 넘긴 뒤 응답이 어떻게 되었는지 나타냅니다.
+type handlerOutcome int
+
+const (
+	outcomeDone    handlerOutcome = iota // The handler's response stands. // 핸들러의 응답이 유지됩니다.
+	outcomeReplied                       // The timeout response replaced the handler's. // 타임아웃 응답이 핸들러의 응답을 대체했습니다.
+	outcomeClose                         // Replied, but the handler is still reading the body. // 응답했지만 핸들러가 아직 바디를 읽고 있습니다.
+	outcomeAbort                         // The response cannot be completed, so the connection must be dropped. // 응답을 완성할 수 없어 연결을 끊어야 합니다.
+)
+
+// serveTimed runs the handler under the request timeout, like http.TimeoutHandler. The handler gets its
+// own goroutine, header map and body guard; when it overruns, the engine answers in its place and the
+// handler's later writes fail with http.ErrHandlerTimeout.
+// serveTimed는 http.TimeoutHandler처럼 요청 타임아웃 안에서 핸들러를 실행합니다. 핸들러는 자신만의 고루틴,
+// 헤더 맵, 바디 가드를 받으며, 시간을 넘기면 엔진이 대신 응답하고 핸들러의 이후 쓰기는 http.ErrHandlerTimeout으로
+// 실패합니다.
+func (e *Engine) serveTimed(ctx *appcontext.RequestContext, respWriter *adaptor.ResponseWriter, req *http.Request) (handlerOutcome, *timeoutBody) {
+	parent, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	t := &requestTimer{start: time.Now(), fired: make(chan struct{}), cancel: cancel}
+	t.set(e.requestTimeout)
+	defer t.stop()
+
+	hreq := req.WithContext(&timeoutContext{Context: parent, t: t})
+	var body *timeoutBody
+	if req.Body != nil && req.Body != http.NoBody {
+		body = &timeoutBody{ReadCloser: req.Body}
+		hreq.Body = body
+	}
+	tw := &timeoutWriter{rw: respWriter, h: make(http.Header)}
+	for k, v := range respWriter.Header() {
+		tw.h[k] = v
+	}
+	// The abandoned handler may outlive this request, so it must not share the pooled context.
+	// 버려진 핸들러는 이 요청보다 오래 살 수 있으므로 풀링된 컨텍스트를 공유하면 안 됩니다.
+	hctx := appcontext.NewRequestContext(ctx.Conn(), ctx.Req())
+
+	done := make(chan struct{})
+	var aborted bool
+	go func() {
+		defer close(done)
+		aborted = e.runHandler(hctx, tw, tw, hreq)
+	}()
+
+	select {
+	case <-done:
+		if aborted {
+			return outcomeAbort, nil
+		}
+		tw.finish()
+		return outcomeDone, nil
+	case <-t.fired:
+	}
+
+	switch tw.timeout(e.timeoutCode, e.timeoutBody) {
+	case timeoutHijacked:
+		// The handler owns the connection now; the timeout no longer applies.
+		// 이제 핸들러가 연결을 소유하므로 타임아웃은 더 이상 적용되지 않습니다.
+		<-done
+		return outcomeDone, nil
+	case timeoutCommitted:
+		e.connLogger(ctx).Warn("handler timed out after responding", "method", req.Method, "path", req.URL.Path)
+		return outcomeAbort, body
+	}
+	if body != nil && !body.abandon() {
+		respWriter.Header().Set("Connection", "close")
+		return outcomeClose, body
+	}
+	return outcomeReplied, nil
+}
+
+// timeoutBody stops an overrunning handler from reading the body the engine is about to drain.
+// timeoutBody는 시간을 넘긴 핸들러가 엔진이 비우려는 바디를 읽지 못하게 합니다.
+type timeoutBody struct {
+	io.ReadCloser
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, http.ErrHandlerTimeout
+	}
+	return b.ReadCloser.Read(p)
+}
+
+func (b *timeoutBody) Close() error {
+	return nil // The engine closes the body. // 엔진이 바디를 닫습니다.
+}
+
+// abandon takes the body back unless a read is in progress, in which case wait must follow closing the connection.
+// abandon은 읽기가 진행 중이 아니면 바디를 회수합니다. 진행 중이라면 연결을 닫은 뒤 wait를 호출해야 합니다.
+func (b *timeoutBody) abandon() bool {
+	if !b.mu.TryLock() {
+		return false
+	}
+	b.closed = true
+	b.mu.Unlock()
+	return true
+}
+
+// wait blocks until the handler's read in progress returns. // wait는 진행 중인 핸들러의 읽기가 반환될 때까지 대기합니다.
+func (b *timeoutBody) wait() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+}
+
+// timeoutWriter serializes the handler's writes with the engine's timeout response.
+// timeoutWriter는 핸들러의 쓰기와 엔진의 타임아웃 응답을 직렬화합니다.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	rw       *adaptor.ResponseWriter
+	h        http.Header // The handler's own, copied into rw while it may still commit. // 핸들러 전용이며 커밋할 수 있는 동안 rw로 복사됩니다.
+	timedOut bool
+}
+
+type timeoutState int
+
+const (
+	timeoutReplaced timeoutState = iota
+	timeoutCommitted
+	timeoutHijacked
+)
+
+// timeout replaces the response with the timeout response if it is not committed yet.
+// timeout은 응답이 아직 커밋되지 않았다면 타임아웃 응답으로 바꿉니다.
+func (tw *timeoutWriter) timeout(code int, body string) timeoutState {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.rw.Hijacked() {
+		return timeoutHijacked
+	}
+	tw.timedOut = true
+	if !tw.rw.Reset() {
+		return timeoutCommitted
+	}
+	if code == 0 {
+		code, body = http.StatusServiceUnavailable, defaultTimeoutBody
+	}
+	tw.rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	tw.rw.WriteHeader(code)
+	_, _ = tw.rw.Write([]byte(body))
+	return timeoutReplaced
+}
+
+// finish hands the handler's headers to the response once it returned in time.
+// finish는 핸들러가 제시간에 반환되면 핸들러의 헤더를 응답에 넘깁니다.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.syncHeader()
+}
+
+func (tw *timeoutWriter) syncHeader() {
+	if tw.rw.Committed() {
+		return
+	}
+	h := tw.rw.Header()
+	clear(h)
+	for k, v := range tw.h {
+		h[k] = v
+	}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.rw.WriteHeader(code)
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.rw.Write(p)
+}
+
+func (tw *timeoutWriter) ReadFrom(r io.Reader) (int64, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.syncHeader()
+	return tw.rw.ReadFrom(r)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.syncHeader()
+		tw.rw.Flush()
+	}
+}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	return tw.rw.Hijack()
+}
+
+func (tw *timeoutWriter) Status() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.rw.Status()
+}
+
+func (tw *timeoutWriter) Written() int64 {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.rw.Written()
+}
+
+func (tw *timeoutWriter) Committed() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.timedOut || tw.rw.Committed()
+}
+
+func (tw *timeoutWriter) Hijacked() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.rw.Hijacked()
+}
+
+func (tw *timeoutWriter) Reset() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || !tw.rw.Reset() {
+		return false
+	}
+	clear(tw.h)
+	return true
+}
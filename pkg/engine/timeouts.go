@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// errBodyTimeout ends a connection whose request body was not received in time.
+// errBodyTimeout은 요청 바디를 제시간에 받지 못한 연결을 종료합니다.
+var errBodyTimeout = errors.New("engine: request body read timed out")
+
+// WithIdleTimeout closes a keep-alive connection when the next request does not start within d.
+// Without it the server's read timeout bounds the wait and the connection is left to the peer.
+// WithIdleTimeout은 다음 요청이 d 안에 시작되지 않으면 keep-alive 연결을 닫습니다.
+// 설정하지 않으면 서버의 읽기 타임아웃이 대기 시간을 제한하며 연결은 피어에게 맡겨집니다.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(e *Engine) {
+		e.idleTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout bounds the time from the first byte of a request to the end of its headers.
+// A client that is too slow gets a 408 and the connection is closed.
+// WithReadHeaderTimeout은 요청의 첫 바이트부터 헤더 끝까지의 시간을 제한합니다.
+// 너무 느린 클라이언트는 408을 받고 연결이 닫힙니다.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(e *Engine) {
+		e.readHeaderTimeout = d
+	}
+}
+
+// WithBodyReadTimeout bounds the time from the end of the headers to the end of the request body.
+// When it expires, body reads fail with a timeout; unless the handler has already sent its response,
+// the client gets a 408 instead. Either way the connection is closed.
+// WithBodyReadTimeout은 헤더 끝부터 요청 바디 끝까지의 시간을 제한합니다.
+// 만료되면 바디 읽기가 타임아웃으로 실패하며, 핸들러가 아직 응답을 보내지 않았다면 클라이언트는 대신
+// 408을 받습니다. 어느 경우든 연결은 닫힙니다.
+func WithBodyReadTimeout(d time.Duration) Option {
+	return func(e *Engine) {
+		e.bodyReadTimeout = d
+	}
+}
+
+// WithMinBodyRate requires request bodies to arrive at bytesPerSecond on average after a grace period,
+// so a client trickling a large upload cannot hold a connection forever. Falling behind is handled
+// like a body read timeout.
+// WithMinBodyRate는 유예 기간 이후 요청 바디가 평균 bytesPerSecond 이상으로 도착하도록 요구하여, 큰 업로드를
+// 조금씩 보내는 클라이언트가 연결을 무한히 점유하지 못하게 합니다. 기준에 못 미치면 바디 읽기 타임아웃과
+// 같이 처리됩니다.
+func WithMinBodyRate(bytesPerSecond int, grace time.Duration) Option {
+	return func(e *Engine) {
+		e.minBodyRate = bytesPerSecond
+		e.minBodyRateGrace = grace
+	}
+}
+
+// timedBody enforces the body read timeout and minimum rate through the connection's read deadline.
+// timedBody는 연결의 읽기 데드라인으로 바디 읽기 타임아웃과 최소 전송률을 적용합니다.
+type timedBody struct {
+	io.ReadCloser
+	conn     netpoll.Connection
+	start    time.Time
+	deadline time.Time // zero without a body read timeout. // 바디 읽기 타임아웃이 없으면 0입니다.
+	rate     int
+	grace    time.Duration
+	n        int64
+	timedOut bool
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	if b.rate > 0 {
+		dl := b.start.Add(b.grace + time.Duration(float64(b.n)/float64(b.rate)*float64(time.Second)))
+		if !b.deadline.IsZero() && b.deadline.Before(dl) {
+			dl = b.deadline
+		}
+		_ = b.conn.SetReadDeadline(dl)
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if isTimeout(err) {
+		b.timedOut = true
+	}
+	return n, err
+}
+
+// wrapBody applies the body read timeout and minimum rate to req, returning nil when neither is set.
+// wrapBody는 req에 바디 읽기 타임아웃과 최소 전송률을 적용하며, 둘 다 없으면 nil을 반환합니다.
+func (e *Engine) wrapBody(conn netpoll.Connection, req *http.Request) *timedBody {
+	if e.bodyReadTimeout <= 0 && e.minBodyRate <= 0 {
+		return nil
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	b := &timedBody{
+		ReadCloser: req.Body,
+		conn:       conn,
+		start:      time.Now(),
+		rate:       e.minBodyRate,
+		grace:      e.minBodyRateGrace,
+	}
+	if e.bodyReadTimeout > 0 {
+		b.deadline = b.start.Add(e.bodyReadTimeout)
+		_ = conn.SetReadDeadline(b.deadline)
+	}
+	req.Body = b
+	return b
+}
+
+// isTimeout reports whether err is a network timeout. // isTimeout은 err가 네트워크 타임아웃인지 보고합니다.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,673 @@
+package h2
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+
+	"github.com/cloudwego/netpoll"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+var (
+	errConnClosed  = errors.New("h2: connection closed")
+	errStreamReset = errors.New("h2: stream reset")
+)
+
+// connectionStater is implemented by connections that carry a TLS session.
+// connectionStater는 TLS 세션을 가진 연결이 구현합니다.
+type connectionStater interface {
+	ConnectionState() tls.ConnectionState
+}
+
+// upgradeStream carries the HTTP/1.1 request that switched the connection to h2c.
+// upgradeStream은 연결을 h2c로 전환한 HTTP/1.1 요청을 담습니다.
+type upgradeStream struct {
+	req  *http.Request
+	body *requestBody
+}
+
+// serverConn is the state of a single HTTP/2 connection.
+// The read loop owns the framer's read side; writes from handler goroutines are serialized by wmu.
+// serverConn은 단일 HTTP/2 연결의 상태입니다.
+// 읽기 루프가 프레이머의 읽기 측을 소유하며, 핸들러 고루틴의 쓰기는 wmu로 직렬화됩니다.
+type serverConn struct {
+	srv    *Server
+	conn   netpoll.Connection
+	ctx    context.Context
+	cancel context.CancelFunc
+	framer *http2.Framer
+	r      io.Reader
+
+	remoteAddr string
+	tlsState   *tls.ConnectionState
+
+	// wmu guards the framer's write side, the HPACK encoder and the netpoll writer.
+	// wmu는 프레이머의 쓰기 측, HPACK 인코더, netpoll writer를 보호합니다.
+	wmu  sync.Mutex
+	henc *hpack.Encoder
+	hbuf bytes.Buffer
+
+	// mu guards the fields below; cond is signalled when send windows grow or streams end.
+	// mu는 아래 필드들을 보호하며, cond는 송신 윈도우가 늘어나거나 스트림이 끝날 때 신호를 받습니다.
+	mu                sync.Mutex
+	cond              *sync.Cond
+	streams           map[uint32]*stream
+	maxStreamID       uint32
+	sendWindow        int64
+	peerInitialWindow int64
+	peerMaxFrameSize  uint32
+	goAwaySent        bool
+	closed            bool
+
+	wg sync.WaitGroup
+}
+
+// frameWriter copies frames into the netpoll writer.
+// netpoll keeps large slices by reference, so Malloc is used to detach them from the framer's buffer.
+// frameWriter는 프레임을 netpoll writer에 복사합니다.
+// netpoll은 큰 슬라이스를 참조로 보관하므로, Malloc을 사용해 프레이머의 버퍼와 분리합니다.
+type frameWriter struct {
+	w netpoll.Writer
+}
+
+func (fw frameWriter) Write(p []byte) (int, error) {
+	buf, err := fw.w.Malloc(len(p))
+	if err != nil {
+		return 0, err
+	}
+	return copy(buf, p), nil
+}
+
+func newServerConn(ctx context.Context, srv *Server, conn netpoll.Connection, r io.Reader) *serverConn {
+	sc := &serverConn{
+		srv:               srv,
+		conn:              conn,
+		r:                 r,
+		streams:           make(map[uint32]*stream),
+		sendWindow:        65535,
+		peerInitialWindow: 65535,
+		peerMaxFrameSize:  defaultMaxFrameSize,
+	}
+	sc.ctx, sc.cancel = context.WithCancel(ctx)
+	sc.cond = sync.NewCond(&sc.mu)
+
+	sc.framer = http2.NewFramer(frameWriter{w: conn.Writer()}, r)
+	sc.framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	sc.framer.MaxHeaderListSize = srv.maxHeaderListSize
+	sc.framer.SetMaxReadFrameSize(srv.maxFrameSize)
+	sc.henc = hpack.NewEncoder(&sc.hbuf)
+
+	// The connection state knows the client relayed by a PROXY header. // 연결 상태는 PROXY 헤더로 전달된 클라이언트를 알고 있습니다.
+	addr := conn.RemoteAddr()
+	if c := appcontext.ConnFromContext(ctx); c != nil {
+		addr = c.RemoteAddr()
+	}
+	if addr != nil {
+		sc.remoteAddr = addr.String()
+		// Unnamed Unix peers are reported as "@", matching adaptor.GetRequest.
+		// 이름 없는 Unix 피어는 adaptor.GetRequest와 같이 "@"로 표시합니다.
+		if ua, ok := addr.(*net.UnixAddr); ok && ua.Name == "" {
+			sc.remoteAddr = "@"
+		}
+	}
+	if cs, ok := conn.(connectionStater); ok {
+		state := cs.ConnectionState()
+		sc.tlsState = &state
+	}
+	return sc
+}
+
+// serve runs the connection until the peer goes away or a connection error occurs.
+// serve는 피어가 떠나거나 연결 오류가 발생할 때까지 연결을 처리합니다.
+func (sc *serverConn) serve(preface string, peerSettings []http2.Setting, upgrade *upgradeStream) error {
+	defer sc.close()
+
+	// The server preface is our SETTINGS frame, followed by a larger connection window.
+	// 서버 프리페이스는 SETTINGS 프레임이며, 이어서 더 큰 연결 윈도우를 알립니다.
+	err := sc.write(func(fr *http2.Framer) error {
+		if err := fr.WriteSettings(
+			http2.Setting{ID: http2.SettingMaxConcurrentStreams, Val: sc.srv.maxConcurrentStreams},
+			http2.Setting{ID: http2.SettingInitialWindowSize, Val: sc.srv.initialWindowSize},
+			http2.Setting{ID: http2.SettingMaxFrameSize, Val: sc.srv.maxFrameSize},
+			http2.Setting{ID: http2.SettingMaxHeaderListSize, Val: sc.srv.maxHeaderListSize},
+		); err != nil {
+			return err
+		}
+		return fr.WriteWindowUpdate(0, defaultConnWindowSize-65535)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := sc.readPreface(preface); err != nil {
+		return err
+	}
+
+	if peerSettings != nil {
+		if err := sc.applySettings(peerSettings); err != nil {
+			return sc.goAway(err)
+		}
+	}
+	if upgrade != nil {
+		sc.startUpgradeStream(upgrade)
+	}
+	if c := appcontext.ConnFromContext(sc.ctx); c != nil {
+		go sc.watchDrain(c.DrainNotify())
+	}
+
+	for {
+		f, err := sc.framer.ReadFrame()
+		if err != nil {
+			var se http2.StreamError
+			if errors.As(err, &se) {
+				sc.resetStream(se.StreamID, se.Code)
+				continue
+			}
+			if isTimeout(err) && sc.activeStreams() > 0 {
+				// Read timeouts only end idle connections. // 읽기 타임아웃은 유휴 연결만 종료합니다.
+				continue
+			}
+			if err == http2.ErrFrameTooLarge {
+				return sc.goAway(http2.ConnectionError(http2.ErrCodeFrameSize))
+			}
+			return sc.goAway(err)
+		}
+		if err := sc.processFrame(f); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return sc.goAway(err)
+		}
+	}
+}
+
+func (sc *serverConn) readPreface(preface string) error {
+	buf := make([]byte, len(preface))
+	if _, err := io.ReadFull(sc.r, buf); err != nil {
+		return err
+	}
+	if string(buf) != preface {
+		return errBadPreface
+	}
+	return nil
+}
+
+// processFrame handles a single frame read from the peer.
+// processFrame은 피어로부터 읽은 단일 프레임을 처리합니다.
+func (sc *serverConn) processFrame(f http2.Frame) error {
+	switch f := f.(type) {
+	case *http2.SettingsFrame:
+		return sc.processSettings(f)
+	case *http2.MetaHeadersFrame:
+		return sc.processHeaders(f)
+	case *http2.DataFrame:
+		return sc.processData(f)
+	case *http2.WindowUpdateFrame:
+		return sc.processWindowUpdate(f)
+	case *http2.PingFrame:
+		if f.StreamID != 0 {
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		if f.IsAck() {
+			return nil
+		}
+		return sc.write(func(fr *http2.Framer) error { return fr.WritePing(true, f.Data) })
+	case *http2.RSTStreamFrame:
+		sc.mu.Lock()
+		st := sc.streams[f.StreamID]
+		sc.mu.Unlock()
+		if st != nil {
+			st.abort(errStreamReset, false)
+		} else if f.StreamID > sc.maxStreamID {
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		return nil
+	case *http2.GoAwayFrame:
+		// The peer will not open new streams; let the active ones finish.
+		// 피어가 더 이상 스트림을 열지 않으므로, 진행 중인 스트림이 끝나도록 둡니다.
+		return io.EOF
+	case *http2.PushPromiseFrame:
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	default:
+		// PRIORITY and unknown frame types are ignored. // PRIORITY와 알 수 없는 프레임은 무시합니다.
+		return nil
+	}
+}
+
+func (sc *serverConn) processSettings(f *http2.SettingsFrame) error {
+	if f.IsAck() {
+		return nil
+	}
+	if f.HasDuplicates() {
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+	settings := make([]http2.Setting, 0, f.NumSettings())
+	if err := f.ForeachSetting(func(s http2.Setting) error {
+		settings = append(settings, s)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := sc.applySettings(settings); err != nil {
+		return err
+	}
+	return sc.write(func(fr *http2.Framer) error { return fr.WriteSettingsAck() })
+}
+
+func (sc *serverConn) applySettings(settings []http2.Setting) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, s := range settings {
+		if err := s.Valid(); err != nil {
+			return err
+		}
+		switch s.ID {
+		case http2.SettingInitialWindowSize:
+			// Adjust every open stream by the delta (RFC 9113 §6.9.2).
+			// 열린 모든 스트림을 차이만큼 조정합니다 (RFC 9113 §6.9.2).
+			delta := int64(s.Val) - sc.peerInitialWindow
+			sc.peerInitialWindow = int64(s.Val)
+			for _, st := range sc.streams {
+				st.sendWindow += delta
+				if st.sendWindow > 1<<31-1 {
+					return http2.ConnectionError(http2.ErrCodeFlowControl)
+				}
+			}
+		case http2.SettingMaxFrameSize:
+			sc.peerMaxFrameSize = s.Val
+		case http2.SettingHeaderTableSize:
+			sc.wmu.Lock()
+			sc.henc.SetMaxDynamicTableSizeLimit(s.Val)
+			sc.wmu.Unlock()
+		}
+	}
+	sc.cond.Broadcast()
+	return nil
+}
+
+func (sc *serverConn) processHeaders(f *http2.MetaHeadersFrame) error {
+	id := f.StreamID
+	if id%2 == 0 {
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+
+	sc.mu.Lock()
+	st := sc.streams[id]
+	if st != nil {
+		sc.mu.Unlock()
+		return st.processTrailers(f)
+	}
+	if id <= sc.maxStreamID {
+		// Frames on streams we already finished are ignored. // 이미 끝난 스트림의 프레임은 무시합니다.
+		sc.mu.Unlock()
+		return nil
+	}
+	sc.maxStreamID = id
+	if sc.goAwaySent {
+		sc.mu.Unlock()
+		return nil
+	}
+	if uint32(len(sc.streams)) >= sc.srv.maxConcurrentStreams {
+		sc.mu.Unlock()
+		sc.resetStream(id, http2.ErrCodeRefusedStream)
+		return nil
+	}
+	st = sc.newStream(id)
+	sc.mu.Unlock()
+
+	req, handler, err := st.newRequest(f)
+	if err != nil {
+		st.abort(errStreamReset, false)
+		sc.finishStream(st)
+		sc.resetStream(id, http2.ErrCodeProtocol)
+		return nil
+	}
+	sc.runHandler(st, req, handler)
+	return nil
+}
+
+func (sc *serverConn) startUpgradeStream(u *upgradeStream) {
+	sc.mu.Lock()
+	sc.maxStreamID = 1
+	st := sc.newStream(1)
+	st.remoteClosed = true
+	sc.mu.Unlock()
+
+	st.body = u.body
+	req := u.req.WithContext(st.ctx)
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/2.0", 2, 0
+	req.Header.Del("Upgrade")
+	req.Header.Del("Connection")
+	req.Header.Del("HTTP2-Settings")
+	req.Body = st.body
+	sc.runHandler(st, req, sc.srv.handler)
+}
+
+// newStream registers a stream; sc.mu must be held.
+// newStream은 스트림을 등록합니다. sc.mu를 잡은 상태여야 합니다.
+func (sc *serverConn) newStream(id uint32) *stream {
+	st := &stream{
+		id:         id,
+		sc:         sc,
+		sendWindow: sc.peerInitialWindow,
+		recvWindow: int64(sc.srv.initialWindowSize),
+	}
+	st.ctx, st.cancel = context.WithCancel(sc.ctx)
+	sc.streams[id] = st
+	return st
+}
+
+func (sc *serverConn) processData(f *http2.DataFrame) error {
+	id := f.StreamID
+	n := int64(f.Length)
+
+	// The connection window is credited back immediately; per-stream windows bound buffering.
+	// 연결 윈도우는 즉시 반환하며, 버퍼링은 스트림별 윈도우로 제한합니다.
+	if n > 0 {
+		if err := sc.write(func(fr *http2.Framer) error { return fr.WriteWindowUpdate(0, uint32(n)) }); err != nil {
+			return err
+		}
+	}
+
+	sc.mu.Lock()
+	st := sc.streams[id]
+	if st == nil {
+		idle := id > sc.maxStreamID
+		sc.mu.Unlock()
+		if idle {
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		return nil
+	}
+	if st.remoteClosed {
+		sc.mu.Unlock()
+		sc.resetStream(id, http2.ErrCodeStreamClosed)
+		st.abort(errStreamReset, true)
+		return nil
+	}
+	st.recvWindow -= n
+	if st.recvWindow < 0 {
+		sc.mu.Unlock()
+		sc.resetStream(id, http2.ErrCodeFlowControl)
+		st.abort(errStreamReset, true)
+		return nil
+	}
+	if f.StreamEnded() {
+		st.remoteClosed = true
+	}
+	sc.mu.Unlock()
+
+	data := f.Data()
+	if pad := n - int64(len(data)); pad > 0 {
+		st.credit(int(pad))
+	}
+	if len(data) > 0 {
+		st.body.write(data)
+	}
+	if f.StreamEnded() {
+		st.body.closeWithError(io.EOF)
+	}
+	return nil
+}
+
+func (sc *serverConn) processWindowUpdate(f *http2.WindowUpdateFrame) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	inc := int64(f.Increment)
+	if f.StreamID == 0 {
+		sc.sendWindow += inc
+		if sc.sendWindow > 1<<31-1 {
+			return http2.ConnectionError(http2.ErrCodeFlowControl)
+		}
+	} else if st := sc.streams[f.StreamID]; st != nil {
+		st.sendWindow += inc
+		if st.sendWindow > 1<<31-1 {
+			sc.mu.Unlock()
+			sc.resetStream(f.StreamID, http2.ErrCodeFlowControl)
+			st.abort(errStreamReset, true)
+			sc.mu.Lock()
+		}
+	}
+	sc.cond.Broadcast()
+	return nil
+}
+
+// runHandler serves one stream on its own goroutine.
+// runHandler는 하나의 스트림을 별도의 고루틴에서 처리합니다.
+func (sc *serverConn) runHandler(st *stream, req *http.Request, handler http.Handler) {
+	sc.wg.Add(1)
+	go func() {
+		defer sc.wg.Done()
+		rw := newResponseWriter(st, req)
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					if rw.wroteHeader {
+						st.abort(errStreamReset, true)
+						sc.resetStream(st.id, http2.ErrCodeInternal)
+						return
+					}
+					rw.body.Reset()
+					clear(rw.header)
+					rw.statusCode = http.StatusInternalServerError
+				}
+			}()
+			handler.ServeHTTP(rw, req)
+		}()
+		rw.endStream()
+		rw.release()
+		st.body.Close()
+		sc.finishStream(st)
+	}()
+}
+
+// finishStream forgets st once its handler is done, resetting it if the client is still sending.
+// finishStream은 핸들러가 끝난 스트림을 정리하며, 클라이언트가 아직 보내는 중이면 스트림을 리셋합니다.
+func (sc *serverConn) finishStream(st *stream) {
+	sc.mu.Lock()
+	delete(sc.streams, st.id)
+	needReset := !st.remoteClosed && st.resetErr == nil
+	sc.cond.Broadcast()
+	sc.mu.Unlock()
+	st.cancel()
+	if needReset {
+		sc.resetStream(st.id, http2.ErrCodeNo)
+	}
+}
+
+func (sc *serverConn) activeStreams() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return len(sc.streams)
+}
+
+// reserveWindow blocks until up to want bytes of send window are available for st.
+// reserveWindow는 st에 최대 want 바이트의 송신 윈도우가 확보될 때까지 대기합니다.
+func (sc *serverConn) reserveWindow(st *stream, want int) (int, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for {
+		if sc.closed {
+			return 0, errConnClosed
+		}
+		if st.resetErr != nil {
+			return 0, st.resetErr
+		}
+		n := int64(want)
+		n = min(n, sc.sendWindow, st.sendWindow, int64(sc.peerMaxFrameSize))
+		if n > 0 {
+			sc.sendWindow -= n
+			st.sendWindow -= n
+			return int(n), nil
+		}
+		sc.cond.Wait()
+	}
+}
+
+// write runs fn against the framer under the write lock and flushes the result.
+// write는 쓰기 락을 잡은 상태에서 fn을 프레이머에 대해 실행하고 결과를 플러시합니다.
+func (sc *serverConn) write(fn func(fr *http2.Framer) error) error {
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	if err := fn(sc.framer); err != nil {
+		return err
+	}
+	return sc.conn.Writer().Flush()
+}
+
+// writeHeaderBlock HPACK-encodes a response header (status > 0) or trailer block (status == 0),
+// splitting it into CONTINUATION frames when it exceeds the peer's frame size.
+// writeHeaderBlock은 응답 헤더(status > 0) 또는 트레일러 블록(status == 0)을 HPACK으로 인코딩하며,
+// 피어의 프레임 크기를 넘으면 CONTINUATION 프레임으로 나눕니다.
+func (sc *serverConn) writeHeaderBlock(streamID uint32, status int, h http.Header, endStream bool) error {
+	sc.mu.Lock()
+	maxFrame := int(sc.peerMaxFrameSize)
+	sc.mu.Unlock()
+
+	return sc.write(func(fr *http2.Framer) error {
+		sc.hbuf.Reset()
+		if status > 0 {
+			sc.henc.WriteField(hpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
+		}
+		for k, vv := range h {
+			if status == 0 {
+				k = strings.TrimPrefix(k, http.TrailerPrefix)
+			} else if strings.HasPrefix(k, http.TrailerPrefix) {
+				// TrailerPrefix keys are sent after the body. // TrailerPrefix 키는 바디 이후에 전송됩니다.
+				continue
+			}
+			name := strings.ToLower(k)
+			if isConnectionHeader(name) {
+				continue
+			}
+			for _, v := range vv {
+				sc.henc.WriteField(hpack.HeaderField{Name: name, Value: v})
+			}
+		}
+
+		block := sc.hbuf.Bytes()
+		first := block
+		if len(first) > maxFrame {
+			first = first[:maxFrame]
+		}
+		block = block[len(first):]
+		if err := fr.WriteHeaders(http2.HeadersFrameParam{
+			StreamID:      streamID,
+			BlockFragment: first,
+			EndStream:     endStream,
+			EndHeaders:    len(block) == 0,
+		}); err != nil {
+			return err
+		}
+		for len(block) > 0 {
+			frag := block
+			if len(frag) > maxFrame {
+				frag = frag[:maxFrame]
+			}
+			block = block[len(frag):]
+			if err := fr.WriteContinuation(streamID, len(block) == 0, frag); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sc *serverConn) resetStream(id uint32, code http2.ErrCode) {
+	_ = sc.write(func(fr *http2.Framer) error { return fr.WriteRSTStream(id, code) })
+}
+
+// goAway tells the peer the connection is ending because of err and returns err.
+// goAway는 err 때문에 연결이 종료됨을 피어에게 알리고 err를 반환합니다.
+func (sc *serverConn) goAway(err error) error {
+	code := http2.ErrCodeNo
+	var ce http2.ConnectionError
+	switch {
+	case errors.As(err, &ce):
+		code = http2.ErrCode(ce)
+	case err == io.EOF || isTimeout(err) || errors.Is(err, netpoll.ErrConnClosed):
+		// Peer left or went idle; no error code to report. // 피어가 떠났거나 유휴 상태입니다.
+	default:
+		code = http2.ErrCodeProtocol
+	}
+
+	sc.mu.Lock()
+	sent := sc.goAwaySent
+	sc.goAwaySent = true
+	lastID := sc.maxStreamID
+	sc.mu.Unlock()
+	if !sent {
+		_ = sc.write(func(fr *http2.Framer) error { return fr.WriteGoAway(lastID, code, nil) })
+	}
+	if code == http2.ErrCodeNo {
+		return nil
+	}
+	return err
+}
+
+// watchDrain sends GOAWAY once the connection starts draining and closes it when the last stream ends.
+// watchDrain은 연결이 드레이닝을 시작하면 GOAWAY를 보내고, 마지막 스트림이 끝나면 연결을 닫습니다.
+func (sc *serverConn) watchDrain(drain <-chan struct{}) {
+	select {
+	case <-drain:
+	case <-sc.ctx.Done():
+		return
+	}
+	_ = sc.goAway(io.EOF)
+
+	sc.mu.Lock()
+	for len(sc.streams) > 0 && !sc.closed {
+		sc.cond.Wait()
+	}
+	closed := sc.closed
+	sc.mu.Unlock()
+	if !closed {
+		_ = sc.conn.Close()
+	}
+}
+
+// close waits for in-flight handlers and closes the connection.
+// close는 진행 중인 핸들러를 기다린 후 연결을 닫습니다.
+func (sc *serverConn) close() {
+	sc.mu.Lock()
+	sc.closed = true
+	streams := make([]*stream, 0, len(sc.streams))
+	for _, st := range sc.streams {
+		streams = append(streams, st)
+	}
+	sc.cond.Broadcast()
+	sc.mu.Unlock()
+
+	for _, st := range streams {
+		st.body.closeWithError(errConnClosed)
+	}
+	sc.cancel()
+	sc.wg.Wait()
+	_ = sc.conn.Close()
+}
+
+// isConnectionHeader reports whether name is a connection-specific header forbidden in HTTP/2.
+// isConnectionHeader는 name이 HTTP/2에서 금지된 연결 전용 헤더인지 보고합니다.
+func isConnectionHeader(name string) bool {
+	switch name {
+	case "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade":
+		return true
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,227 @@
+package h2
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/bytebufferpool"
+
+	"golang.org/x/net/http2"
+)
+
+var errStreamClosed = errors.New("h2: write on finished stream")
+
+// responseWriter implements http.ResponseWriter and http.Flusher for one stream.
+// Like adaptor.ResponseWriter, it buffers the body in a pooled ByteBuffer until Flush or the end of the handler.
+// responseWriter는 하나의 스트림에 대해 http.ResponseWriter와 http.Flusher를 구현합니다.
+// adaptor.ResponseWriter처럼 Flush 또는 핸들러 종료 시까지 바디를 풀링된 ByteBuffer에 버퍼링합니다.
+type responseWriter struct {
+	st          *stream
+	req         *http.Request
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+	ended       bool
+	body        *bytebufferpool.ByteBuffer
+}
+
+// rwPool recycles responseWriter objects to reduce GC pressure.
+// rwPool은 responseWriter 객체를 재활용하여 가비지 컬렉션(GC) 부하를 줄입니다.
+var rwPool = sync.Pool{
+	New: func() any {
+		return &responseWriter{
+			header: make(http.Header),
+		}
+	},
+}
+
+func newResponseWriter(st *stream, req *http.Request) *responseWriter {
+	rw := rwPool.Get().(*responseWriter)
+	rw.st = st
+	rw.req = req
+	rw.statusCode = 0
+	rw.wroteHeader = false
+	rw.ended = false
+	rw.body = bytebufferpool.Get()
+	return rw
+}
+
+func (rw *responseWriter) release() {
+	rw.st = nil
+	rw.req = nil
+	if rw.body != nil {
+		bytebufferpool.Put(rw.body)
+		rw.body = nil
+	}
+	clear(rw.header)
+	rwPool.Put(rw)
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	if rw.wroteHeader {
+		return
+	}
+	// Informational responses are not forwarded. // 1xx 응답은 전달하지 않습니다.
+	if statusCode < 200 {
+		return
+	}
+	rw.statusCode = statusCode
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.ended {
+		return 0, errStreamClosed
+	}
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+	if !bodyAllowed(rw.statusCode) {
+		return 0, http.ErrBodyNotAllowed
+	}
+	return rw.body.Write(p)
+}
+
+// Flush sends the headers, if not yet sent, and any buffered body as DATA frames.
+// Flush는 아직 보내지 않았다면 헤더를, 그리고 버퍼링된 바디를 DATA 프레임으로 전송합니다.
+func (rw *responseWriter) Flush() {
+	if rw.ended {
+		return
+	}
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+	if !rw.wroteHeader {
+		if err := rw.writeHeaders(false); err != nil {
+			return
+		}
+	}
+	if rw.body.Len() > 0 {
+		_ = rw.writeData(rw.body.Bytes(), false)
+		rw.body.Reset()
+	}
+}
+
+// endStream completes the response: headers, remaining body and trailers, with END_STREAM on the last frame.
+// endStream은 헤더, 남은 바디, 트레일러를 보내 응답을 완료하며 마지막 프레임에 END_STREAM을 설정합니다.
+func (rw *responseWriter) endStream() {
+	if rw.ended {
+		return
+	}
+	rw.ended = true
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+
+	trailers := rw.trailers()
+	hasBody := rw.body.Len() > 0
+	if !rw.wroteHeader {
+		if rw.header.Get("Content-Length") == "" && bodyAllowed(rw.statusCode) && trailers == nil {
+			rw.header.Set("Content-Length", strconv.Itoa(rw.body.Len()))
+		}
+		if err := rw.writeHeaders(!hasBody && trailers == nil); err != nil || (!hasBody && trailers == nil) {
+			return
+		}
+	}
+	// After an early Flush, an empty DATA frame carries END_STREAM. // 이른 Flush 이후에는 빈 DATA 프레임이 END_STREAM을 전달합니다.
+	if hasBody || trailers == nil {
+		if err := rw.writeData(rw.body.Bytes(), trailers == nil); err != nil {
+			return
+		}
+	}
+	if trailers != nil {
+		_ = rw.st.sc.writeHeaderBlock(rw.st.id, 0, trailers, true)
+	}
+}
+
+func (rw *responseWriter) writeHeaders(endStream bool) error {
+	rw.wroteHeader = true
+	if rw.header.Get("Date") == "" {
+		rw.header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	if rw.header.Get("Content-Type") == "" && rw.body.Len() > 0 {
+		sniffBuf := rw.body.Bytes()
+		if len(sniffBuf) > 512 {
+			sniffBuf = sniffBuf[:512]
+		}
+		rw.header.Set("Content-Type", http.DetectContentType(sniffBuf))
+	}
+
+	return rw.st.sc.writeHeaderBlock(rw.st.id, rw.statusCode, rw.header, endStream)
+}
+
+// writeData sends p as DATA frames, waiting for flow-control window as needed.
+// An empty p with endStream still sends a single empty DATA frame.
+// writeData는 필요한 만큼 흐름 제어 윈도우를 기다리며 p를 DATA 프레임으로 전송합니다.
+// p가 비어 있고 endStream이면 빈 DATA 프레임 하나를 전송합니다.
+func (rw *responseWriter) writeData(p []byte, endStream bool) error {
+	st := rw.st
+	sc := st.sc
+	if len(p) == 0 {
+		if !endStream {
+			return nil
+		}
+		return sc.write(func(fr *http2.Framer) error { return fr.WriteData(st.id, true, nil) })
+	}
+	for len(p) > 0 {
+		n, err := sc.reserveWindow(st, len(p))
+		if err != nil {
+			return err
+		}
+		chunk := p[:n]
+		p = p[n:]
+		last := endStream && len(p) == 0
+		if err := sc.write(func(fr *http2.Framer) error { return fr.WriteData(st.id, last, chunk) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trailers collects the values of announced Trailer keys and TrailerPrefix headers.
+// trailers는 예고된 Trailer 키와 TrailerPrefix 헤더의 값을 모읍니다.
+func (rw *responseWriter) trailers() http.Header {
+	var t http.Header
+	for _, v := range rw.header.Values("Trailer") {
+		for _, key := range strings.Split(v, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if vv, ok := rw.header[key]; ok && key != "" {
+				if t == nil {
+					t = make(http.Header)
+				}
+				t[key] = vv
+			}
+		}
+	}
+	for k, vv := range rw.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			if t == nil {
+				t = make(http.Header)
+			}
+			t[k] = vv
+		}
+	}
+	if t != nil {
+		for k := range t {
+			if !strings.HasPrefix(k, http.TrailerPrefix) {
+				// Keys announced via "Trailer" must not also go out as headers.
+				// "Trailer"로 예고된 키는 헤더로도 전송되어서는 안 됩니다.
+				if !rw.wroteHeader {
+					rw.header.Del(k)
+				}
+			}
+		}
+	}
+	return t
+}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && status >= 200
+}
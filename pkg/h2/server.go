@@ -0,0 +1,213 @@
+// Package h2 serves HTTP/2 connections (h2 via ALPN, h2c via prior knowledge or Upgrade)
+// on top of netpoll connections and dispatches every stream to a standard http.Handler.
+// Package h2는 netpoll 연결 위에서 HTTP/2 연결(ALPN을 통한 h2, prior knowledge 또는 Upgrade를 통한 h2c)을
+// 처리하고 각 스트림을 표준 http.Handler로 전달합니다.
+package h2
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/netpoll"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// NextProtoTLS is the ALPN protocol identifier for HTTP/2 over TLS.
+	// NextProtoTLS는 TLS 위의 HTTP/2를 나타내는 ALPN 프로토콜 식별자입니다.
+	NextProtoTLS = "h2"
+
+	defaultMaxConcurrentStreams = 250
+	defaultInitialWindowSize    = 1 << 20
+	defaultMaxFrameSize         = 16 << 10
+	defaultMaxHeaderListSize    = 1 << 20
+	defaultConnWindowSize       = 1 << 20
+
+	// prefaceTail is what remains of the client preface after http.ReadRequest has parsed "PRI * HTTP/2.0".
+	// prefaceTail은 http.ReadRequest가 "PRI * HTTP/2.0"을 파싱한 뒤 남는 클라이언트 프리페이스입니다.
+	prefaceTail = "SM\r\n\r\n"
+)
+
+var (
+	errBadPreface     = errors.New("h2: invalid client preface")
+	errBadH2CSettings = errors.New("h2: invalid HTTP2-Settings header")
+)
+
+// Option is a function type for configuring the Server.
+// Option은 Server 설정을 위한 함수 타입입니다.
+type Option func(*Server)
+
+// WithMaxConcurrentStreams sets how many streams a client may have open at once.
+// WithMaxConcurrentStreams는 클라이언트가 동시에 열 수 있는 스트림 수를 설정합니다.
+func WithMaxConcurrentStreams(n uint32) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxConcurrentStreams = n
+		}
+	}
+}
+
+// WithInitialWindowSize sets the per-stream receive window advertised to clients.
+// WithInitialWindowSize는 클라이언트에 알리는 스트림별 수신 윈도우 크기를 설정합니다.
+func WithInitialWindowSize(n uint32) Option {
+	return func(s *Server) {
+		if n >= 65535 && n <= 1<<31-1 {
+			s.initialWindowSize = n
+		}
+	}
+}
+
+// WithMaxFrameSize sets the largest frame payload the server accepts.
+// WithMaxFrameSize는 서버가 수락하는 최대 프레임 페이로드 크기를 설정합니다.
+func WithMaxFrameSize(n uint32) Option {
+	return func(s *Server) {
+		if n >= 16<<10 && n <= 1<<24-1 {
+			s.maxFrameSize = n
+		}
+	}
+}
+
+// WithMaxHeaderListSize sets the largest decoded header list the server accepts.
+// WithMaxHeaderListSize는 서버가 수락하는 디코딩된 헤더 목록의 최대 크기를 설정합니다.
+func WithMaxHeaderListSize(n uint32) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxHeaderListSize = n
+		}
+	}
+}
+
+// Server holds the HTTP/2 settings shared by all connections.
+// Server는 모든 연결이 공유하는 HTTP/2 설정을 보관합니다.
+type Server struct {
+	handler              http.Handler
+	maxConcurrentStreams uint32
+	initialWindowSize    uint32
+	maxFrameSize         uint32
+	maxHeaderListSize    uint32
+}
+
+// NewServer creates a new Server dispatching streams to handler.
+// NewServer는 스트림을 handler로 전달하는 새로운 Server를 생성합니다.
+func NewServer(handler http.Handler, opts ...Option) *Server {
+	s := &Server{
+		handler:              handler,
+		maxConcurrentStreams: defaultMaxConcurrentStreams,
+		initialWindowSize:    defaultInitialWindowSize,
+		maxFrameSize:         defaultMaxFrameSize,
+		maxHeaderListSize:    defaultMaxHeaderListSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ServeConn serves a connection that negotiated "h2" through ALPN.
+// The full client preface is expected on r.
+// ServeConn은 ALPN으로 "h2"를 협상한 연결을 처리합니다.
+// r에서 전체 클라이언트 프리페이스를 읽습니다.
+func (s *Server) ServeConn(ctx context.Context, conn netpoll.Connection, r io.Reader) error {
+	sc := newServerConn(ctx, s, conn, bufio.NewReader(r))
+	return sc.serve(http2.ClientPreface, nil, nil)
+}
+
+// ServePriorKnowledge serves a cleartext connection whose first request line was the HTTP/2 preface.
+// r must be the reader http.ReadRequest consumed "PRI * HTTP/2.0\r\n\r\n" from.
+// ServePriorKnowledge는 첫 요청 라인이 HTTP/2 프리페이스였던 평문 연결을 처리합니다.
+// r은 http.ReadRequest가 "PRI * HTTP/2.0\r\n\r\n"을 읽어 들인 리더여야 합니다.
+func (s *Server) ServePriorKnowledge(ctx context.Context, conn netpoll.Connection, r *bufio.Reader) error {
+	sc := newServerConn(ctx, s, conn, r)
+	return sc.serve(prefaceTail, nil, nil)
+}
+
+// ServeUpgrade answers an "Upgrade: h2c" request with 101 Switching Protocols and serves
+// the connection as HTTP/2, handling the upgrade request itself as stream 1.
+// ServeUpgrade는 "Upgrade: h2c" 요청에 101 Switching Protocols로 응답하고 연결을 HTTP/2로 처리하며,
+// 업그레이드 요청 자체는 스트림 1로 처리합니다.
+func (s *Server) ServeUpgrade(ctx context.Context, conn netpoll.Connection, r *bufio.Reader, req *http.Request) error {
+	settings, err := decodeH2CSettings(req.Header.Get("HTTP2-Settings"))
+	if err != nil {
+		return err
+	}
+
+	// The upgrade request body must be read before switching protocols.
+	// 프로토콜을 전환하기 전에 업그레이드 요청 바디를 모두 읽어야 합니다.
+	body := newRequestBody(nil)
+	if req.Body != nil && req.Body != http.NoBody {
+		if _, err := body.buf.ReadFrom(req.Body); err != nil {
+			body.Close()
+			return err
+		}
+		_ = req.Body.Close()
+	}
+	body.closeWithError(io.EOF)
+
+	writer := conn.Writer()
+	writer.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+	if err := writer.Flush(); err != nil {
+		body.Close()
+		return err
+	}
+
+	sc := newServerConn(ctx, s, conn, r)
+	return sc.serve(http2.ClientPreface, settings, &upgradeStream{req: req, body: body})
+}
+
+// IsPriorKnowledge reports whether req is the "PRI * HTTP/2.0" request line of an HTTP/2 client preface.
+// IsPriorKnowledge는 req가 HTTP/2 클라이언트 프리페이스의 "PRI * HTTP/2.0" 요청 라인인지 보고합니다.
+func IsPriorKnowledge(req *http.Request) bool {
+	return req.Method == "PRI" && len(req.Header) == 0 && req.URL.Path == "*" && req.Proto == "HTTP/2.0"
+}
+
+// IsUpgrade reports whether req asks to switch to cleartext HTTP/2.
+// IsUpgrade는 req가 평문 HTTP/2로의 전환을 요청하는지 보고합니다.
+func IsUpgrade(req *http.Request) bool {
+	if req.ProtoMajor != 1 || req.ProtoMinor != 1 {
+		return false
+	}
+	if !headerHasToken(req.Header, "Upgrade", "h2c") || !headerHasToken(req.Header, "Connection", "HTTP2-Settings") {
+		return false
+	}
+	return len(req.Header.Values("HTTP2-Settings")) == 1
+}
+
+// headerHasToken reports whether the comma-separated header key contains token.
+// headerHasToken은 쉼표로 구분된 헤더 key에 token이 포함되어 있는지 보고합니다.
+func headerHasToken(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeH2CSettings decodes the base64url SETTINGS payload carried by HTTP2-Settings.
+// decodeH2CSettings는 HTTP2-Settings에 담긴 base64url SETTINGS 페이로드를 디코딩합니다.
+func decodeH2CSettings(v string) ([]http2.Setting, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "="))
+	if err != nil || len(b)%6 != 0 {
+		return nil, errBadH2CSettings
+	}
+	settings := make([]http2.Setting, 0, len(b)/6)
+	for i := 0; i < len(b); i += 6 {
+		st := http2.Setting{
+			ID:  http2.SettingID(binary.BigEndian.Uint16(b[i:])),
+			Val: binary.BigEndian.Uint32(b[i+2:]),
+		}
+		if err := st.Valid(); err != nil {
+			return nil, errBadH2CSettings
+		}
+		settings = append(settings, st)
+	}
+	return settings, nil
+}
@@ -0,0 +1,303 @@
+package h2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/bytebufferpool"
+
+	"golang.org/x/net/http2"
+)
+
+var errBodyClosed = errors.New("h2: read on closed body")
+
+// stream is a single request/response exchange on a serverConn.
+// Fields marked "sc.mu" are guarded by the connection mutex.
+// stream은 serverConn 위의 단일 요청/응답 교환입니다.
+// "sc.mu"로 표시된 필드는 연결 뮤텍스로 보호됩니다.
+type stream struct {
+	id     uint32
+	sc     *serverConn
+	ctx    context.Context
+	cancel context.CancelFunc
+	body   *requestBody
+	req    *http.Request
+
+	sendWindow   int64 // sc.mu
+	recvWindow   int64 // sc.mu
+	remoteClosed bool  // sc.mu
+	resetErr     error // sc.mu
+}
+
+// abort marks the stream as reset, cancels its context and fails pending body reads.
+// abort는 스트림을 리셋 상태로 표시하고 컨텍스트를 취소하며 대기 중인 바디 읽기를 실패시킵니다.
+func (st *stream) abort(err error, remoteClosed bool) {
+	st.sc.mu.Lock()
+	if st.resetErr == nil {
+		st.resetErr = err
+	}
+	if remoteClosed {
+		st.remoteClosed = true
+	}
+	st.sc.cond.Broadcast()
+	st.sc.mu.Unlock()
+
+	st.cancel()
+	if st.body != nil {
+		st.body.closeWithError(err)
+	}
+}
+
+// credit returns n consumed bytes to the stream's receive window.
+// credit은 소비된 n 바이트를 스트림의 수신 윈도우에 돌려줍니다.
+func (st *stream) credit(n int) {
+	sc := st.sc
+	sc.mu.Lock()
+	if st.remoteClosed || st.resetErr != nil || sc.closed {
+		sc.mu.Unlock()
+		return
+	}
+	st.recvWindow += int64(n)
+	sc.mu.Unlock()
+	_ = sc.write(func(fr *http2.Framer) error { return fr.WriteWindowUpdate(st.id, uint32(n)) })
+}
+
+// processTrailers handles a trailing HEADERS frame, which must end the stream.
+// processTrailers는 스트림을 끝내야 하는 후행 HEADERS 프레임을 처리합니다.
+func (st *stream) processTrailers(f *http2.MetaHeadersFrame) error {
+	sc := st.sc
+	sc.mu.Lock()
+	closed := st.remoteClosed
+	if !closed {
+		st.remoteClosed = true
+	}
+	sc.mu.Unlock()
+
+	if closed {
+		sc.resetStream(st.id, http2.ErrCodeStreamClosed)
+		st.abort(errStreamReset, true)
+		return nil
+	}
+	if !f.StreamEnded() || len(f.PseudoFields()) > 0 {
+		sc.resetStream(st.id, http2.ErrCodeProtocol)
+		st.abort(errStreamReset, true)
+		return nil
+	}
+
+	// Trailers are published before EOF so handlers see them once the body is drained.
+	// 트레일러는 EOF 이전에 기록되어, 바디를 다 읽은 핸들러가 볼 수 있습니다.
+	st.body.mu.Lock()
+	if st.req != nil && st.req.Trailer != nil {
+		for _, hf := range f.RegularFields() {
+			key := textproto.CanonicalMIMEHeaderKey(hf.Name)
+			if _, ok := st.req.Trailer[key]; ok {
+				st.req.Trailer[key] = append(st.req.Trailer[key], hf.Value)
+			}
+		}
+	}
+	st.body.mu.Unlock()
+	st.body.closeWithError(io.EOF)
+	return nil
+}
+
+// newRequest builds the http.Request for a HEADERS frame opening a stream.
+// It returns the handler to run, which is a 431 responder when the header list was truncated.
+// newRequest는 스트림을 여는 HEADERS 프레임으로부터 http.Request를 생성합니다.
+// 헤더 목록이 잘린 경우 431로 응답하는 핸들러를 반환합니다.
+func (st *stream) newRequest(f *http2.MetaHeadersFrame) (*http.Request, http.Handler, error) {
+	sc := st.sc
+	method := f.PseudoValue("method")
+	path := f.PseudoValue("path")
+	scheme := f.PseudoValue("scheme")
+	authority := f.PseudoValue("authority")
+
+	isConnect := method == http.MethodConnect
+	if method == "" || (!isConnect && (path == "" || scheme == "")) || (isConnect && (path != "" || authority == "")) {
+		return nil, nil, errors.New("h2: missing pseudo header")
+	}
+
+	header := make(http.Header)
+	var cookies []string
+	for _, hf := range f.RegularFields() {
+		if hf.Name == "cookie" {
+			cookies = append(cookies, hf.Value)
+			continue
+		}
+		if isConnectionHeader(hf.Name) || (hf.Name == "te" && hf.Value != "trailers") {
+			return nil, nil, errors.New("h2: connection-specific header")
+		}
+		key := textproto.CanonicalMIMEHeaderKey(hf.Name)
+		header[key] = append(header[key], hf.Value)
+	}
+	if len(cookies) > 0 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	var u *url.URL
+	requestURI := path
+	if isConnect {
+		u = &url.URL{Host: authority}
+		requestURI = authority
+	} else {
+		var err error
+		if u, err = url.ParseRequestURI(path); err != nil {
+			return nil, nil, err
+		}
+	}
+	u.Scheme = "http"
+	if sc.tlsState != nil {
+		u.Scheme = "https"
+	}
+	host := authority
+	if host == "" {
+		host = header.Get("Host")
+	}
+	u.Host = host
+
+	var trailer http.Header
+	for _, v := range header.Values("Trailer") {
+		for _, key := range strings.Split(v, ",") {
+			key = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key))
+			if key == "" {
+				continue
+			}
+			if trailer == nil {
+				trailer = make(http.Header)
+			}
+			trailer[key] = nil
+		}
+	}
+	header.Del("Trailer")
+
+	st.body = newRequestBody(st)
+	var body io.ReadCloser = st.body
+	contentLength := int64(-1)
+	if f.StreamEnded() {
+		sc.mu.Lock()
+		st.remoteClosed = true
+		sc.mu.Unlock()
+		st.body.closeWithError(io.EOF)
+		body = http.NoBody
+		contentLength = 0
+	} else if cl := header.Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n >= 0 {
+			contentLength = n
+		}
+	}
+
+	req := (&http.Request{
+		Method:        method,
+		URL:           u,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		ProtoMinor:    0,
+		Header:        header,
+		Body:          body,
+		ContentLength: contentLength,
+		Host:          host,
+		Trailer:       trailer,
+		RemoteAddr:    sc.remoteAddr,
+		RequestURI:    requestURI,
+		TLS:           sc.tlsState,
+	}).WithContext(st.ctx)
+	st.req = req
+
+	if f.Truncated {
+		return req, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusRequestHeaderFieldsTooLarge)
+		}), nil
+	}
+	return req, sc.srv.handler, nil
+}
+
+// requestBody buffers DATA frames for a stream in a pooled ByteBuffer until the handler reads them.
+// requestBody는 핸들러가 읽을 때까지 스트림의 DATA 프레임을 풀링된 ByteBuffer에 버퍼링합니다.
+type requestBody struct {
+	st     *stream
+	mu     sync.Mutex
+	cond   sync.Cond
+	buf    *bytebufferpool.ByteBuffer
+	off    int
+	err    error // io.EOF once the peer ended the stream. // 피어가 스트림을 끝내면 io.EOF.
+	closed bool
+}
+
+func newRequestBody(st *stream) *requestBody {
+	b := &requestBody{
+		st:  st,
+		buf: bytebufferpool.Get(),
+	}
+	b.cond.L = &b.mu
+	return b
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	for !b.closed && b.off == b.buf.Len() && b.err == nil {
+		b.cond.Wait()
+	}
+	if b.closed {
+		b.mu.Unlock()
+		return 0, errBodyClosed
+	}
+	if b.off == b.buf.Len() {
+		err := b.err
+		b.mu.Unlock()
+		return 0, err
+	}
+	n := copy(p, b.buf.B[b.off:])
+	b.off += n
+	if b.off == b.buf.Len() {
+		// Everything buffered was consumed; reuse the buffer from the start.
+		// 버퍼링된 데이터를 모두 소비했으므로 버퍼를 처음부터 재사용합니다.
+		b.buf.Reset()
+		b.off = 0
+	}
+	b.mu.Unlock()
+
+	if b.st != nil && n > 0 {
+		b.st.credit(n)
+	}
+	return n, nil
+}
+
+// Close releases the buffer to the pool; later DATA frames are discarded.
+// Close는 버퍼를 풀에 반환하며, 이후의 DATA 프레임은 버려집니다.
+func (b *requestBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	bytebufferpool.Put(b.buf)
+	b.buf = nil
+	b.cond.Broadcast()
+	return nil
+}
+
+func (b *requestBody) write(p []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || b.err != nil {
+		return
+	}
+	b.buf.Write(p) // nolint:errcheck
+	b.cond.Broadcast()
+}
+
+func (b *requestBody) closeWithError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err == nil {
+		b.err = err
+	}
+	b.cond.Broadcast()
+}
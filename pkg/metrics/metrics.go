@@ -0,0 +1,243 @@
+// Package metrics collects server, engine and buffer pool metrics and serves them in the
+// Prometheus text exposition format without depending on the Prometheus client library.
+// metrics 패키지는 서버, 엔진, 버퍼 풀 지표를 수집하고 Prometheus 클라이언트 라이브러리 없이
+// Prometheus 텍스트 노출 형식으로 제공합니다.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/bytebufferpool"
+)
+
+// DefaultBuckets are the latency histogram bounds in seconds, matching the Prometheus client defaults.
+// DefaultBuckets는 Prometheus 클라이언트 기본값과 같은 지연 시간 히스토그램 경계(초)입니다.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// statusClasses label the request counter by the first digit of the status code.
+// statusClasses는 상태 코드의 첫 자리로 요청 카운터의 레이블을 붙입니다.
+var statusClasses = [...]string{"other", "1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// Metrics holds the counters shared by a server and its engine. It is safe for concurrent use.
+// Pass the same value to server.WithMetrics and engine.WithMetrics, and mount Handler to expose it.
+// Metrics는 서버와 엔진이 공유하는 카운터를 담으며 동시에 사용해도 안전합니다.
+// 같은 값을 server.WithMetrics와 engine.WithMetrics에 전달하고, Handler를 마운트하여 노출합니다.
+type Metrics struct {
+	connsAccepted atomic.Uint64
+	connsClosed   atomic.Uint64
+
+	requests      [len(statusClasses)]atomic.Uint64
+	buckets       []float64
+	bucketCounts  []atomic.Uint64 // Per bucket, not cumulative; the last one is +Inf. // 버킷별 개수이며 누적이 아닙니다. 마지막은 +Inf입니다.
+	durationSumNs atomic.Int64
+	bytesIn       atomic.Uint64
+	bytesOut      atomic.Uint64
+
+	queued          atomic.Int64
+	queueWaitCounts []atomic.Uint64 // Like bucketCounts. // bucketCounts와 같습니다.
+	queueWaitSumNs  atomic.Int64
+	shed            atomic.Uint64
+	panics          atomic.Uint64
+}
+
+// New creates Metrics with the given latency buckets in seconds, in increasing order.
+// Without buckets DefaultBuckets is used.
+// New는 증가하는 순서의 지연 시간 버킷(초)으로 Metrics를 생성합니다. 버킷이 없으면 DefaultBuckets를 사용합니다.
+func New(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Metrics{
+		buckets:         buckets,
+		bucketCounts:    make([]atomic.Uint64, len(buckets)+1),
+		queueWaitCounts: make([]atomic.Uint64, len(buckets)+1),
+	}
+}
+
+// ConnAccepted records a connection admitted by the server.
+// ConnAccepted는 서버가 수락한 연결을 기록합니다.
+func (m *Metrics) ConnAccepted() {
+	m.connsAccepted.Add(1)
+}
+
+// ConnClosed records the close of a connection counted by ConnAccepted.
+// ConnClosed는 ConnAccepted로 집계된 연결의 종료를 기록합니다.
+func (m *Metrics) ConnClosed() {
+	m.connsClosed.Add(1)
+}
+
+// ObserveRequest records a finished request with its status, latency and body sizes.
+// ObserveRequest는 완료된 요청의 상태, 지연 시간, 바디 크기를 기록합니다.
+func (m *Metrics) ObserveRequest(status int, d time.Duration, bytesIn, bytesOut int64) {
+	class := 0
+	if status >= 100 && status < 600 {
+		class = status / 100
+	}
+	m.requests[class].Add(1)
+
+	m.bucketCounts[m.bucket(d)].Add(1)
+	m.durationSumNs.Add(int64(d))
+
+	if bytesIn > 0 {
+		m.bytesIn.Add(uint64(bytesIn))
+	}
+	if bytesOut > 0 {
+		m.bytesOut.Add(uint64(bytesOut))
+	}
+}
+
+// QueueEntered records a request starting to wait for a handler worker.
+// QueueEntered는 요청이 핸들러 워커를 기다리기 시작했음을 기록합니다.
+func (m *Metrics) QueueEntered() {
+	m.queued.Add(1)
+}
+
+// QueueLeft records a request counted by QueueEntered that stopped waiting.
+// QueueLeft는 QueueEntered로 집계된 요청이 대기를 마쳤음을 기록합니다.
+func (m *Metrics) QueueLeft() {
+	m.queued.Add(-1)
+}
+
+// ObserveQueueWait records how long a request waited for a handler worker, zero when one was free.
+// ObserveQueueWait는 요청이 핸들러 워커를 기다린 시간을 기록하며, 바로 얻었다면 0입니다.
+func (m *Metrics) ObserveQueueWait(d time.Duration) {
+	m.queueWaitCounts[m.bucket(d)].Add(1)
+	m.queueWaitSumNs.Add(int64(d))
+}
+
+// RequestShed records a request answered with 503 because the server was overloaded.
+// RequestShed는 서버 과부하로 503 응답을 받은 요청을 기록합니다.
+func (m *Metrics) RequestShed() {
+	m.shed.Add(1)
+}
+
+// PanicRecovered records a handler panic recovered by the engine.
+// PanicRecovered는 엔진이 복구한 핸들러 패닉을 기록합니다.
+func (m *Metrics) PanicRecovered() {
+	m.panics.Add(1)
+}
+
+// bucket returns the index of the histogram bucket for d. // bucket은 d가 속한 히스토그램 버킷의 인덱스를 반환합니다.
+func (m *Metrics) bucket(d time.Duration) int {
+	secs := d.Seconds()
+	i := 0
+	for i < len(m.buckets) && secs > m.buckets[i] {
+		i++
+	}
+	return i
+}
+
+// Handler returns an http.Handler serving the metrics in the Prometheus text format.
+// Handler는 Prometheus 텍스트 형식으로 지표를 제공하는 http.Handler를 반환합니다.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = m.WriteTo(w)
+	})
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+// WriteTo는 Prometheus 텍스트 형식으로 w에 지표를 씁니다.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	e := expositionWriter{w: bw}
+
+	accepted, closed := m.connsAccepted.Load(), m.connsClosed.Load()
+	e.metric("netpoll_http_connections_accepted_total", "counter", "Connections admitted by the server.")
+	e.sample("netpoll_http_connections_accepted_total", "", float64(accepted))
+	e.metric("netpoll_http_connections_active", "gauge", "Connections currently open.")
+	e.sample("netpoll_http_connections_active", "", float64(accepted-min(closed, accepted)))
+	e.metric("netpoll_http_connections_closed_total", "counter", "Connections closed.")
+	e.sample("netpoll_http_connections_closed_total", "", float64(closed))
+
+	e.metric("netpoll_http_requests_total", "counter", "HTTP/1.x requests handled, by status class.")
+	for i, class := range statusClasses {
+		e.sample("netpoll_http_requests_total", `class="`+class+`"`, float64(m.requests[i].Load()))
+	}
+
+	e.metric("netpoll_http_request_duration_seconds", "histogram", "Time from reading the request to finishing the response.")
+	e.histogram("netpoll_http_request_duration_seconds", m.buckets, m.bucketCounts, m.durationSumNs.Load())
+
+	e.metric("netpoll_http_request_body_bytes_total", "counter", "Request body bytes read by handlers.")
+	e.sample("netpoll_http_request_body_bytes_total", "", float64(m.bytesIn.Load()))
+	e.metric("netpoll_http_response_body_bytes_total", "counter", "Response body bytes written by handlers.")
+	e.sample("netpoll_http_response_body_bytes_total", "", float64(m.bytesOut.Load()))
+
+	e.metric("netpoll_http_handler_queue_depth", "gauge", "Requests waiting for a handler worker.")
+	e.sample("netpoll_http_handler_queue_depth", "", float64(m.queued.Load()))
+	e.metric("netpoll_http_handler_queue_wait_seconds", "histogram", "Time requests waited for a handler worker.")
+	e.histogram("netpoll_http_handler_queue_wait_seconds", m.buckets, m.queueWaitCounts, m.queueWaitSumNs.Load())
+	e.metric("netpoll_http_requests_shed_total", "counter", "Requests answered with 503 because the handler queue was full or the wait expired.")
+	e.sample("netpoll_http_requests_shed_total", "", float64(m.shed.Load()))
+
+	e.metric("netpoll_http_handler_panics_total", "counter", "Handler panics recovered by the engine.")
+	e.sample("netpoll_http_handler_panics_total", "", float64(m.panics.Load()))
+
+	pool := bytebufferpool.Stats()
+	e.metric("netpoll_http_bytebufferpool_gets_total", "counter", "Buffer pool Get calls, by whether a pooled buffer was reused.")
+	e.sample("netpoll_http_bytebufferpool_gets_total", `result="hit"`, float64(pool.Hits))
+	e.sample("netpoll_http_bytebufferpool_gets_total", `result="miss"`, float64(pool.Misses))
+	e.metric("netpoll_http_bytebufferpool_default_size_bytes", "gauge", "Calibrated capacity of new buffers.")
+	e.sample("netpoll_http_bytebufferpool_default_size_bytes", "", float64(pool.DefaultSize))
+	e.metric("netpoll_http_bytebufferpool_max_size_bytes", "gauge", "Calibrated largest capacity kept by the pool.")
+	e.sample("netpoll_http_bytebufferpool_max_size_bytes", "", float64(pool.MaxSize))
+
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// expositionWriter writes HELP, TYPE and sample lines. Write errors surface through bufio.Writer.Flush.
+// expositionWriter는 HELP, TYPE, 샘플 줄을 씁니다. 쓰기 오류는 bufio.Writer.Flush에서 드러납니다.
+type expositionWriter struct {
+	w *bufio.Writer
+}
+
+func (e expositionWriter) metric(name, typ, help string) {
+	e.w.WriteString("# HELP " + name + " " + help + "\n")
+	e.w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func (e expositionWriter) sample(name, labels string, v float64) {
+	e.w.WriteString(name)
+	if labels != "" {
+		e.w.WriteString("{" + labels + "}")
+	}
+	e.w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+// histogram writes the cumulative buckets, sum and count of a histogram from per-bucket counts.
+// histogram은 버킷별 개수로부터 히스토그램의 누적 버킷, 합계, 개수를 씁니다.
+func (e expositionWriter) histogram(name string, bounds []float64, counts []atomic.Uint64, sumNs int64) {
+	var cumulative uint64
+	for i, le := range bounds {
+		cumulative += counts[i].Load()
+		e.sample(name+"_bucket", `le="`+formatFloat(le)+`"`, float64(cumulative))
+	}
+	cumulative += counts[len(bounds)].Load()
+	e.sample(name+"_bucket", `le="+Inf"`, float64(cumulative))
+	e.sample(name+"_sum", "", time.Duration(sumNs).Seconds())
+	e.sample(name+"_count", "", float64(cumulative))
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
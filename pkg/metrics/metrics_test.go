@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTo(t *testing.T) {
+	m := New(0.1, 1)
+	m.ConnAccepted()
+	m.ConnAccepted()
+	m.ConnClosed()
+	m.ObserveRequest(http.StatusOK, 50*time.Millisecond, 10, 100)
+	m.ObserveRequest(http.StatusNotFound, 500*time.Millisecond, 0, 9)
+	m.ObserveRequest(http.StatusBadGateway, 2*time.Second, 0, 0)
+	m.QueueEntered()
+	m.QueueEntered()
+	m.QueueLeft()
+	m.ObserveQueueWait(0)
+	m.ObserveQueueWait(500 * time.Millisecond)
+	m.RequestShed()
+	m.PanicRecovered()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+
+	out := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE netpoll_http_connections_accepted_total counter\nnetpoll_http_connections_accepted_total 2\n",
+		"netpoll_http_connections_active 1\n",
+		"netpoll_http_connections_closed_total 1\n",
+		`netpoll_http_requests_total{class="2xx"} 1` + "\n",
+		`netpoll_http_requests_total{class="4xx"} 1` + "\n",
+		`netpoll_http_requests_total{class="5xx"} 1` + "\n",
+		`netpoll_http_request_duration_seconds_bucket{le="0.1"} 1` + "\n",
+		`netpoll_http_request_duration_seconds_bucket{le="1"} 2` + "\n",
+		`netpoll_http_request_duration_seconds_bucket{le="+Inf"} 3` + "\n",
+		"netpoll_http_request_duration_seconds_sum 2.55\n",
+		"netpoll_http_request_duration_seconds_count 3\n",
+		"netpoll_http_request_body_bytes_total 10\n",
+		"netpoll_http_response_body_bytes_total 109\n",
+		"netpoll_http_handler_queue_depth 1\n",
+		`netpoll_http_handler_queue_wait_seconds_bucket{le="0.1"} 1` + "\n",
+		`netpoll_http_handler_queue_wait_seconds_bucket{le="1"} 2` + "\n",
+		"netpoll_http_handler_queue_wait_seconds_sum 0.5\n",
+		"netpoll_http_requests_shed_total 1\n",
+		"netpoll_http_handler_panics_total 1\n",
+		"# TYPE netpoll_http_bytebufferpool_max_size_bytes gauge\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
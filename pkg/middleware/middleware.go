@@ -0,0 +1,68 @@
+// Package middleware provides built-in middleware for engine.Engine.Use.
+// middleware 패키지는 engine.Engine.Use를 위한 내장 미들웨어를 제공합니다.
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+)
+
+// Recovery recovers from handler panics, logging them with the stack and replacing the response
+// with a 500. When part of the response was already sent, the panic continues to the engine instead.
+// Recovery는 핸들러 패닉에서 복구하여 스택과 함께 기록하고 응답을 500으로 바꿉니다.
+// 응답의 일부가 이미 전송되었다면 패닉은 대신 엔진으로 전달됩니다.
+func Recovery() engine.Middleware {
+	return func(next engine.HandlerFunc) engine.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler || !c.Reset() {
+					panic(p)
+				}
+				c.Logger().Error("panic in handler", "method", r.Method, "path", r.URL.Path, "panic", p, "stack", string(debug.Stack()))
+				w.Header().Set("Content-Length", "0")
+				w.WriteHeader(http.StatusInternalServerError)
+			}()
+			next(w, r, c)
+		}
+	}
+}
+
+// Timing reports the handler's duration to the client in a Server-Timing header, as "app;dur=<ms>".
+// Responses that were streamed or hijacked have already sent their headers and are left alone.
+// Timing은 핸들러 실행 시간을 "app;dur=<ms>" 형식의 Server-Timing 헤더로 클라이언트에 알립니다.
+// 스트리밍되었거나 하이재킹된 응답은 이미 헤더를 보냈으므로 건드리지 않습니다.
+func Timing() engine.Middleware {
+	return func(next engine.HandlerFunc) engine.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+			start := time.Now()
+			next(w, r, c)
+			if c.Committed() {
+				return
+			}
+			ms := float64(time.Since(start)) / float64(time.Millisecond)
+			w.Header().Add("Server-Timing", "app;dur="+strconv.FormatFloat(ms, 'f', 3, 64))
+		}
+	}
+}
+
+// Timeout replaces the Engine's WithRequestTimeout with d for the requests passing through it, and has
+// no effect without WithRequestTimeout. For a single route, call engine.SetRequestTimeout from that
+// route's handler or its own wrapper instead.
+// Timeout은 이를 통과하는 요청에 대해 Engine의 WithRequestTimeout을 d로 바꾸며, WithRequestTimeout이 없으면
+// 효과가 없습니다. 단일 경로에는 대신 그 경로의 핸들러나 자체 래퍼에서 engine.SetRequestTimeout을 호출합니다.
+func Timeout(d time.Duration) engine.Middleware {
+	return func(next engine.HandlerFunc) engine.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+			engine.SetRequestTimeout(r, d)
+			next(w, r, c)
+		}
+	}
+}
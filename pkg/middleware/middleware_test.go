@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/servertest"
+)
+
+// serve runs one raw request through e on an in-memory connection from remote.
+func serve(t *testing.T, e *engine.Engine, remote, raw string) (*http.Response, string) {
+	t.Helper()
+	conn := servertest.NewConn([]byte(raw))
+	if remote != "" {
+		conn.SetRemoteAddr(net.TCPAddrFromAddrPort(netip.MustParseAddrPort(remote)))
+	}
+	_ = e.ServeConn(context.Background(), conn)
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(conn.Bytes())), nil)
+	if err != nil {
+		t.Fatalf("invalid response %q: %v", conn.String(), err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+const get = "GET / HTTP/1.1\r\nHost: x\r\n"
+
+func TestUse_Order(t *testing.T) {
+	var order []string
+	mark := func(name string) engine.Middleware {
+		return func(next engine.HandlerFunc) engine.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+				order = append(order, name)
+				next(w, r, c)
+				order = append(order, "/"+name)
+			}
+		}
+	}
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}))
+	e.Use(mark("a"), mark("b"))
+	e.Use(mark("c"))
+
+	serve(t, e, "", get+"\r\n")
+	if got := strings.Join(order, " "); got != "a b c handler /c /b /a" {
+		t.Errorf("unexpected order %q", got)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Partial", "1")
+		w.Write([]byte("partial"))
+		panic("boom")
+	}), engine.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	e.Use(Recovery())
+
+	resp, body := serve(t, e, "", get+"\r\n")
+	if resp.StatusCode != http.StatusInternalServerError || body != "" || resp.Header.Get("X-Partial") != "" {
+		t.Errorf("expected a clean 500, got %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+	e.Use(RequestID())
+
+	resp, _ := serve(t, e, "", get+"X-Request-Id: abc-123\r\n\r\n")
+	if seen != "abc-123" || resp.Header.Get(RequestIDHeader) != "abc-123" {
+		t.Errorf("client id not kept: context %q, header %q", seen, resp.Header.Get(RequestIDHeader))
+	}
+
+	resp, _ = serve(t, e, "", get+"X-Request-Id: bad id\r\n\r\n")
+	if len(seen) != 32 || resp.Header.Get(RequestIDHeader) != seen {
+		t.Errorf("expected a generated id, got context %q, header %q", seen, resp.Header.Get(RequestIDHeader))
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	e.Use(RealIP(netip.MustParsePrefix("10.0.0.0/8")))
+
+	for _, tc := range []struct {
+		name, remote, headers, want string
+	}{
+		{"trusted proxy", "10.0.0.1:4000", "X-Forwarded-For: 203.0.113.7, 10.0.0.2\r\n", "203.0.113.7:0"},
+		{"spoofed hop", "10.0.0.1:4000", "X-Forwarded-For: 1.1.1.1, 203.0.113.7\r\n", "203.0.113.7:0"},
+		{"real ip", "10.0.0.1:4000", "X-Real-Ip: 2001:db8::1\r\n", "[2001:db8::1]:0"},
+		{"untrusted peer", "198.51.100.1:4000", "X-Forwarded-For: 203.0.113.7\r\n", "198.51.100.1:4000"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, body := serve(t, e, tc.remote, get+tc.headers+"\r\n"); body != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, body)
+			}
+		})
+	}
+}
+
+func TestTiming(t *testing.T) {
+	var streamed bool
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+		if r.URL.Path == "/stream" {
+			w.(http.Flusher).Flush()
+		}
+	}))
+	e.Use(Timing(), func(next engine.HandlerFunc) engine.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+			next(w, r, c)
+			streamed = c.Streamed()
+		}
+	})
+
+	resp, _ := serve(t, e, "", get+"\r\n")
+	if v := resp.Header.Get("Server-Timing"); !strings.HasPrefix(v, "app;dur=") || streamed {
+		t.Errorf("buffered response: Server-Timing %q, streamed %v", v, streamed)
+	}
+	resp, _ = serve(t, e, "", "GET /stream HTTP/1.1\r\nHost: x\r\n\r\n")
+	if v := resp.Header.Get("Server-Timing"); v != "" || !streamed {
+		t.Errorf("streamed response: Server-Timing %q, streamed %v", v, streamed)
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+)
+
+// RealIP rewrites r.RemoteAddr to the client address reported by a trusted proxy in X-Forwarded-For
+// or X-Real-Ip. Peers inside trusted are believed; with no prefixes, loopback and private networks are.
+// Unix socket peers are local and always trusted. The client is the rightmost X-Forwarded-For entry
+// that is not itself a trusted proxy, and the reported port is 0.
+// RealIP는 신뢰하는 프록시가 X-Forwarded-For 또는 X-Real-Ip로 알린 클라이언트 주소로 r.RemoteAddr을 바꿉니다.
+// trusted에 속한 피어를 신뢰하며, 프리픽스가 없으면 루프백과 사설 네트워크를 신뢰합니다. Unix 소켓 피어는
+// 로컬이므로 항상 신뢰합니다. 클라이언트는 그 자신이 신뢰하는 프록시가 아닌 가장 오른쪽 X-Forwarded-For 항목이며,
+// 보고되는 포트는 0입니다.
+func RealIP(trusted ...netip.Prefix) engine.Middleware {
+	isTrusted := func(a netip.Addr) bool {
+		a = a.Unmap()
+		if len(trusted) == 0 {
+			return a.IsLoopback() || a.IsPrivate()
+		}
+		for _, p := range trusted {
+			if p.Contains(a) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next engine.HandlerFunc) engine.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+			peer, err := netip.ParseAddrPort(r.RemoteAddr)
+			if err != nil || isTrusted(peer.Addr()) {
+				if ip, ok := forwardedIP(r.Header, isTrusted); ok {
+					r.RemoteAddr = netip.AddrPortFrom(ip, 0).String()
+				}
+			}
+			next(w, r, c)
+		}
+	}
+}
+
+// forwardedIP returns the client address from the proxy headers. // forwardedIP는 프록시 헤더에서 클라이언트 주소를 반환합니다.
+func forwardedIP(h http.Header, isTrusted func(netip.Addr) bool) (netip.Addr, bool) {
+	if values := h.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		var ip netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break // Everything left of a malformed hop is unreliable. // 잘못된 홉의 왼쪽은 모두 믿을 수 없습니다.
+			}
+			ip = hop.Unmap()
+			if !isTrusted(hop) {
+				break
+			}
+		}
+		if ip.IsValid() {
+			return ip, true
+		}
+	}
+	if ip, err := netip.ParseAddr(strings.TrimSpace(h.Get("X-Real-Ip"))); err == nil {
+		return ip.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
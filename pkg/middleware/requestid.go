@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+)
+
+// RequestIDHeader carries the request id in both directions. // RequestIDHeader는 양방향으로 요청 ID를 전달합니다.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen bounds an id accepted from the client. // maxRequestIDLen은 클라이언트에게서 받는 ID의 길이를 제한합니다.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestID gives every request an id, reusing a well-formed X-Request-Id from the client or
+// generating a random one. The id is echoed in the response and available from RequestIDFromContext.
+// RequestID는 모든 요청에 ID를 부여하며, 클라이언트가 보낸 올바른 형식의 X-Request-Id를 재사용하거나
+// 무작위 ID를 생성합니다. ID는 응답에 반영되며 RequestIDFromContext로 얻을 수 있습니다.
+func RequestID() engine.Middleware {
+	return func(next engine.HandlerFunc) engine.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+				r.Header.Set(RequestIDHeader, id)
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)), c)
+		}
+	}
+}
+
+// RequestIDFromContext returns the id set by RequestID, or "".
+// RequestIDFromContext는 RequestID가 설정한 ID를 반환하며, 없으면 ""를 반환합니다.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts short ids of visible ASCII, so a client cannot inject into logs or headers.
+// validRequestID는 보이는 ASCII로 된 짧은 ID만 받아들여, 클라이언트가 로그나 헤더에 내용을 주입할 수 없게 합니다.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,280 @@
+// Package proxyproto parses the HAProxy PROXY protocol header (v1 text and v2 binary)
+// that L4 load balancers put in front of a connection to carry the original addresses.
+// Package proxyproto는 L4 로드 밸런서가 원래 주소를 전달하기 위해 연결 앞에 붙이는
+// HAProxy PROXY 프로토콜 헤더(v1 텍스트, v2 바이너리)를 파싱합니다.
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// v1MaxLen is the longest v1 line, CRLF included. // v1MaxLen은 CRLF를 포함한 가장 긴 v1 줄의 길이입니다.
+	v1MaxLen = 107
+	// v2HeaderLen is the fixed part of a v2 header. // v2HeaderLen은 v2 헤더의 고정 길이 부분입니다.
+	v2HeaderLen = 16
+)
+
+var (
+	v1Sig = []byte("PROXY ")
+	v2Sig = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	// ErrInvalidHeader is returned for a header that starts with a PROXY signature but is malformed.
+	// ErrInvalidHeader는 PROXY 시그니처로 시작하지만 형식이 잘못된 헤더에 대해 반환됩니다.
+	ErrInvalidHeader = errors.New("proxyproto: invalid header")
+)
+
+// Command tells whether the connection was relayed for a client or opened by the proxy itself.
+// Command는 연결이 클라이언트를 대신해 중계된 것인지, 프록시 자체가 연 것인지를 나타냅니다.
+type Command byte
+
+const (
+	// Local is a connection the proxy opened on its own, e.g. a health check; its addresses are not relayed.
+	// Local은 헬스 체크처럼 프록시가 직접 연 연결이며, 주소가 전달되지 않습니다.
+	Local Command = 0x0
+	// Proxy is a connection relayed on behalf of a client.
+	// Proxy는 클라이언트를 대신해 중계된 연결입니다.
+	Proxy Command = 0x1
+)
+
+// TLV types defined by the v2 specification.
+// v2 명세에 정의된 TLV 타입입니다.
+const (
+	TypeALPN      byte = 0x01
+	TypeAuthority byte = 0x02
+	TypeCRC32C    byte = 0x03
+	TypeNoop      byte = 0x04
+	TypeUniqueID  byte = 0x05
+	TypeSSL       byte = 0x20
+	TypeNetNS     byte = 0x30
+)
+
+// TLV is a type-length-value extension carried by a v2 header.
+// TLV는 v2 헤더에 담긴 type-length-value 확장입니다.
+type TLV struct {
+	Type  byte
+	Value []byte
+}
+
+// Header is a parsed PROXY protocol header.
+// Source and Destination are nil for Local connections and for unknown or unspecified address families.
+// Header는 파싱된 PROXY 프로토콜 헤더입니다.
+// Local 연결이거나 주소 체계가 알 수 없거나 지정되지 않은 경우 Source와 Destination은 nil입니다.
+type Header struct {
+	Version     int
+	Command     Command
+	Source      net.Addr
+	Destination net.Addr
+	TLVs        []TLV
+}
+
+// TLV returns the value of the first TLV of type t.
+// TLV는 타입 t인 첫 번째 TLV의 값을 반환합니다.
+func (h *Header) TLV(t byte) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == t {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Reader is the subset of netpoll.Reader the parser needs.
+// Reader는 파서가 필요로 하는 netpoll.Reader의 부분 집합입니다.
+type Reader interface {
+	Peek(n int) ([]byte, error)
+	Skip(n int) error
+}
+
+// Read parses a PROXY header at the start of r and consumes it.
+// It returns a nil Header, consuming nothing, when r does not start with a PROXY signature.
+// Read는 r의 시작 부분에 있는 PROXY 헤더를 파싱하고 소비합니다.
+// r이 PROXY 시그니처로 시작하지 않으면 아무것도 소비하지 않고 nil Header를 반환합니다.
+func Read(r Reader) (*Header, error) {
+	// One byte is enough to tell the versions apart without blocking on short requests.
+	// 짧은 요청에서 블로킹되지 않고 버전을 구분하는 데는 한 바이트면 충분합니다.
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch b[0] {
+	case v1Sig[0]:
+		if b, err = r.Peek(len(v1Sig)); err != nil || !bytes.Equal(b, v1Sig) {
+			return nil, err
+		}
+		return readV1(r)
+	case v2Sig[0]:
+		if b, err = r.Peek(len(v2Sig)); err != nil || !bytes.Equal(b, v2Sig) {
+			return nil, err
+		}
+		return readV2(r)
+	}
+	return nil, nil
+}
+
+func readV1(r Reader) (*Header, error) {
+	var line []byte
+	for n := len(v1Sig) + 1; ; n++ {
+		if n > v1MaxLen {
+			return nil, ErrInvalidHeader
+		}
+		b, err := r.Peek(n)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasSuffix(b, []byte("\r\n")) {
+			line = b
+			break
+		}
+	}
+
+	h, err := parseV1(string(line[len(v1Sig) : len(line)-2]))
+	if err != nil {
+		return nil, err
+	}
+	return h, r.Skip(len(line))
+}
+
+// parseV1 parses "TCP4 src dst sport dport" or "UNKNOWN ...".
+// parseV1은 "TCP4 src dst sport dport" 또는 "UNKNOWN ..."을 파싱합니다.
+func parseV1(s string) (*Header, error) {
+	h := &Header{Version: 1, Command: Proxy}
+	fields := strings.Split(s, " ")
+	switch fields[0] {
+	case "UNKNOWN":
+		return h, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, ErrInvalidHeader
+	}
+	if len(fields) != 5 {
+		return nil, ErrInvalidHeader
+	}
+
+	src, dst := net.ParseIP(fields[1]), net.ParseIP(fields[2])
+	sport, err1 := parsePort(fields[3])
+	dport, err2 := parsePort(fields[4])
+	if src == nil || dst == nil || err1 != nil || err2 != nil {
+		return nil, ErrInvalidHeader
+	}
+	if isV4 := fields[0] == "TCP4"; isV4 != (src.To4() != nil) || isV4 != (dst.To4() != nil) {
+		return nil, ErrInvalidHeader
+	}
+	h.Source = &net.TCPAddr{IP: src, Port: sport}
+	h.Destination = &net.TCPAddr{IP: dst, Port: dport}
+	return h, nil
+}
+
+func parsePort(s string) (int, error) {
+	// Leading zeros are not allowed by the specification. // 명세상 앞자리 0은 허용되지 않습니다.
+	if len(s) > 1 && s[0] == '0' {
+		return 0, ErrInvalidHeader
+	}
+	p, err := strconv.ParseUint(s, 10, 16)
+	return int(p), err
+}
+
+func readV2(r Reader) (*Header, error) {
+	b, err := r.Peek(v2HeaderLen)
+	if err != nil {
+		return nil, err
+	}
+	if b[12]>>4 != 2 {
+		return nil, ErrInvalidHeader
+	}
+	cmd := Command(b[12] & 0x0f)
+	if cmd != Local && cmd != Proxy {
+		return nil, ErrInvalidHeader
+	}
+	family, proto := b[13]>>4, b[13]&0x0f
+	total := v2HeaderLen + int(binary.BigEndian.Uint16(b[14:16]))
+
+	if b, err = r.Peek(total); err != nil {
+		return nil, err
+	}
+	payload := b[v2HeaderLen:]
+	h := &Header{Version: 2, Command: cmd}
+
+	var addrLen int
+	switch family {
+	case 0x1: // AF_INET
+		addrLen = 12
+	case 0x2: // AF_INET6
+		addrLen = 36
+	case 0x3: // AF_UNIX
+		addrLen = 216
+	}
+	if len(payload) < addrLen {
+		return nil, ErrInvalidHeader
+	}
+	// Receivers must ignore the addresses of LOCAL connections. // LOCAL 연결의 주소는 무시해야 합니다.
+	if cmd == Proxy && addrLen > 0 {
+		if h.Source, h.Destination, err = parseV2Addrs(family, proto, payload[:addrLen]); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.TLVs, err = parseTLVs(payload[addrLen:]); err != nil {
+		return nil, err
+	}
+	return h, r.Skip(total)
+}
+
+func parseV2Addrs(family, proto byte, b []byte) (src, dst net.Addr, err error) {
+	switch family {
+	case 0x1, 0x2:
+		n := 4
+		if family == 0x2 {
+			n = 16
+		}
+		srcIP := net.IP(bytes.Clone(b[:n]))
+		dstIP := net.IP(bytes.Clone(b[n : 2*n]))
+		sport := int(binary.BigEndian.Uint16(b[2*n:]))
+		dport := int(binary.BigEndian.Uint16(b[2*n+2:]))
+		switch proto {
+		case 0x1:
+			return &net.TCPAddr{IP: srcIP, Port: sport}, &net.TCPAddr{IP: dstIP, Port: dport}, nil
+		case 0x2:
+			return &net.UDPAddr{IP: srcIP, Port: sport}, &net.UDPAddr{IP: dstIP, Port: dport}, nil
+		}
+	case 0x3:
+		network := "unix"
+		if proto == 0x2 {
+			network = "unixgram"
+		}
+		return &net.UnixAddr{Name: unixPath(b[:108]), Net: network}, &net.UnixAddr{Name: unixPath(b[108:]), Net: network}, nil
+	}
+	return nil, nil, ErrInvalidHeader
+}
+
+// unixPath trims the NUL padding of a sun_path field.
+// unixPath는 sun_path 필드의 NUL 패딩을 제거합니다.
+func unixPath(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func parseTLVs(b []byte) ([]TLV, error) {
+	var tlvs []TLV
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, ErrInvalidHeader
+		}
+		n := int(binary.BigEndian.Uint16(b[1:3]))
+		if len(b) < 3+n {
+			return nil, ErrInvalidHeader
+		}
+		// Values are copied because the reader's buffer is released after the header is consumed.
+		// 헤더를 소비한 뒤 리더의 버퍼가 해제되므로 값을 복사합니다.
+		tlvs = append(tlvs, TLV{Type: b[0], Value: bytes.Clone(b[3 : 3+n])})
+		b = b[3+n:]
+	}
+	return tlvs, nil
+}
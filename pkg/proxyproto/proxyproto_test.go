@@ -0,0 +1,126 @@
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// v2Header builds a v2 header for cmd with an AF_INET/STREAM address block and the given TLVs.
+func v2Header(cmd byte, tlvs ...TLV) []byte {
+	var payload bytes.Buffer
+	payload.Write(net.ParseIP("203.0.113.7").To4())
+	payload.Write(net.ParseIP("198.51.100.1").To4())
+	binary.Write(&payload, binary.BigEndian, uint16(51234))
+	binary.Write(&payload, binary.BigEndian, uint16(443))
+	for _, tlv := range tlvs {
+		payload.WriteByte(tlv.Type)
+		binary.Write(&payload, binary.BigEndian, uint16(len(tlv.Value)))
+		payload.Write(tlv.Value)
+	}
+
+	var b bytes.Buffer
+	b.Write(v2Sig)
+	b.WriteByte(0x20 | cmd)
+	b.WriteByte(0x11) // AF_INET, STREAM
+	binary.Write(&b, binary.BigEndian, uint16(payload.Len()))
+	b.Write(payload.Bytes())
+	return b.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	const rest = "GET / HTTP/1.1\r\n\r\n"
+	tests := []struct {
+		name    string
+		input   []byte
+		wantSrc string
+		wantDst string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "v1 tcp4", input: []byte("PROXY TCP4 203.0.113.7 198.51.100.1 51234 443\r\n"), wantSrc: "203.0.113.7:51234", wantDst: "198.51.100.1:443"},
+		{name: "v1 tcp6", input: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 51234 443\r\n"), wantSrc: "[2001:db8::1]:51234", wantDst: "[2001:db8::2]:443"},
+		{name: "v1 unknown", input: []byte("PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n")},
+		{name: "v1 family mismatch", input: []byte("PROXY TCP4 2001:db8::1 198.51.100.1 1 2\r\n"), wantErr: true},
+		{name: "v1 leading zero port", input: []byte("PROXY TCP4 203.0.113.7 198.51.100.1 080 443\r\n"), wantErr: true},
+		{name: "v1 too long", input: []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), wantErr: true},
+		{name: "v2 proxy", input: v2Header(0x1), wantSrc: "203.0.113.7:51234", wantDst: "198.51.100.1:443"},
+		{name: "v2 local ignores addresses", input: v2Header(0x0)},
+		{name: "no header", input: nil, wantNil: true},
+		{name: "http starting with P", input: []byte("POST / HTTP/1.1\r\n\r\n"), wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := netpoll.NewReader(bytes.NewReader(append(tt.input, rest...)))
+			h, err := Read(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", h)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if h != nil {
+					t.Fatalf("expected no header, got %+v", h)
+				}
+			} else {
+				if h == nil {
+					t.Fatal("expected a header")
+				}
+				if got := addrString(h.Source); got != tt.wantSrc {
+					t.Errorf("source = %q, want %q", got, tt.wantSrc)
+				}
+				if got := addrString(h.Destination); got != tt.wantDst {
+					t.Errorf("destination = %q, want %q", got, tt.wantDst)
+				}
+			}
+
+			// Whatever follows the header, or the whole input without one, is left for the HTTP parser.
+			want := rest
+			if tt.wantNil {
+				want = string(tt.input) + rest
+			}
+			if remaining, err := r.ReadString(len(want)); err != nil || remaining != want {
+				t.Errorf("remaining = %q, %v, want %q", remaining, err, want)
+			}
+		})
+	}
+}
+
+func TestRead_TLVs(t *testing.T) {
+	r := netpoll.NewReader(bytes.NewReader(v2Header(0x1,
+		TLV{Type: TypeAuthority, Value: []byte("example.com")},
+		TLV{Type: TypeNoop, Value: nil},
+	)))
+	h, err := Read(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok := h.TLV(TypeAuthority); !ok || string(v) != "example.com" {
+		t.Errorf("authority TLV = %q, %v", v, ok)
+	}
+	if len(h.TLVs) != 2 {
+		t.Errorf("expected 2 TLVs, got %d", len(h.TLVs))
+	}
+
+	// A TLV running past the declared length is rejected.
+	bad := v2Header(0x1, TLV{Type: TypeAuthority, Value: []byte("x")})
+	bad[len(bad)-2] = 5
+	if _, err := Read(netpoll.NewReader(bytes.NewReader(bad))); err != ErrInvalidHeader {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func addrString(a net.Addr) string {
+	if a == nil {
+		return ""
+	}
+	return a.String()
+}
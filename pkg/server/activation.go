@@ -0,0 +1,170 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	// listenFdsStart is the first file descriptor passed by systemd (SD_LISTEN_FDS_START).
+	// listenFdsStart는 systemd가 전달하는 첫 번째 파일 디스크립터입니다(SD_LISTEN_FDS_START).
+	listenFdsStart = 3
+
+	// envReadyFD names the pipe an upgrading parent waits on; see Server.Upgrade.
+	// envReadyFD는 업그레이드 중인 부모 프로세스가 기다리는 파이프를 가리킵니다. Server.Upgrade를 참고하세요.
+	envReadyFD = "NETPOLL_READY_FD"
+)
+
+var errNoInheritedListener = errors.New("server: no inherited listener")
+
+// namedListener is a listener received through LISTEN_FDS together with its LISTEN_FDNAMES entry.
+// namedListener는 LISTEN_FDS로 전달받은 리스너와 해당 LISTEN_FDNAMES 항목입니다.
+type namedListener struct {
+	name string
+	ln   net.Listener
+}
+
+// inherited caches the listeners so the environment is parsed, and cleared, only once.
+// inherited는 환경 변수를 한 번만 파싱하고 정리하도록 리스너를 캐시합니다.
+var inherited struct {
+	once      sync.Once
+	listeners []namedListener
+	err       error
+}
+
+// InheritedListeners returns the listeners passed through systemd socket activation or by an
+// upgrading parent process, grouped by their LISTEN_FDNAMES entry ("unknown" when unnamed).
+// LISTEN_PID is honoured when set; the environment variables are cleared after the first call.
+// InheritedListeners는 systemd 소켓 활성화나 업그레이드 중인 부모 프로세스가 전달한 리스너를
+// LISTEN_FDNAMES 항목별로 묶어 반환합니다(이름이 없으면 "unknown").
+// LISTEN_PID가 설정되어 있으면 이를 확인하며, 첫 호출 이후 환경 변수는 제거됩니다.
+func InheritedListeners() (map[string][]net.Listener, error) {
+	listeners, err := loadInherited()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string][]net.Listener, len(listeners))
+	for _, nl := range listeners {
+		m[nl.name] = append(m[nl.name], nl.ln)
+	}
+	return m, nil
+}
+
+// ServeInherited serves on the first inherited listener called name, or on the first inherited
+// listener at all when name is empty. It fails when there is no such listener, so callers can
+// fall back to Serve.
+// ServeInherited는 name이라는 이름의 첫 번째 상속 리스너에서 요청을 처리하며, name이 비어 있으면
+// 첫 번째 상속 리스너를 사용합니다. 해당 리스너가 없으면 실패하므로 호출자는 Serve로 대체할 수 있습니다.
+func (s *Server) ServeInherited(name string) error {
+	listeners, err := loadInherited()
+	if err != nil {
+		return err
+	}
+	for _, nl := range listeners {
+		if name == "" || nl.name == name {
+			s.mu.Lock()
+			s.listenerName = nl.name
+			s.mu.Unlock()
+			return s.ServeListener(nl.ln)
+		}
+	}
+	if name == "" {
+		return errNoInheritedListener
+	}
+	return fmt.Errorf("%w named %q", errNoInheritedListener, name)
+}
+
+func loadInherited() ([]namedListener, error) {
+	inherited.once.Do(func() {
+		inherited.listeners, inherited.err = listenFds()
+	})
+	return inherited.listeners, inherited.err
+}
+
+// listenFds implements the sd_listen_fds(3) protocol.
+// listenFds는 sd_listen_fds(3) 프로토콜을 구현합니다.
+func listenFds() ([]namedListener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make([]namedListener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, nl := range listeners {
+				nl.ln.Close()
+			}
+			return nil, fmt.Errorf("server: inherited fd %d: %w", fd, err)
+		}
+		listeners = append(listeners, namedListener{name: name, ln: ln})
+	}
+	return listeners, nil
+}
+
+// Ready reports that the process is serving. It releases a parent waiting in Server.Upgrade
+// and sends READY=1 with the new MAINPID to systemd when NOTIFY_SOCKET is set.
+// It is a no-op when neither applies.
+// Ready는 프로세스가 요청을 처리하고 있음을 알립니다. Server.Upgrade에서 기다리는 부모 프로세스를
+// 깨우고, NOTIFY_SOCKET이 설정되어 있으면 systemd에 새 MAINPID와 함께 READY=1을 보냅니다.
+// 둘 다 해당하지 않으면 아무 작업도 하지 않습니다.
+func Ready() error {
+	if v := os.Getenv(envReadyFD); v != "" {
+		os.Unsetenv(envReadyFD)
+		fd, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("server: invalid %s %q", envReadyFD, v)
+		}
+		f := os.NewFile(uintptr(fd), "ready")
+		_, err = f.Write([]byte{1})
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return sdNotify(fmt.Sprintf("READY=1\nMAINPID=%d", os.Getpid()))
+}
+
+// sdNotify sends state to the systemd notification socket, if any.
+// An "@" prefix selects an abstract socket, which net handles natively on Linux.
+// sdNotify는 systemd 알림 소켓이 있으면 state를 보냅니다.
+// "@" 접두사는 추상 소켓을 의미하며, Linux에서는 net 패키지가 직접 처리합니다.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
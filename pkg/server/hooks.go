@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// WithConnState sets a hook called when a connection changes state, like http.Server.ConnState.
+// Connections refused by admission limits or shutdown never reach http.StateNew.
+// Hooks run on the connection's goroutine and should return quickly.
+// WithConnState는 http.Server.ConnState처럼 연결 상태가 바뀔 때 호출되는 훅을 설정합니다.
+// 수락 제한이나 종료로 거부된 연결은 http.StateNew에 도달하지 않습니다.
+// 훅은 연결의 고루틴에서 실행되므로 빠르게 반환해야 합니다.
+func WithConnState(f func(netpoll.Connection, http.ConnState)) Option {
+	return func(s *Server) {
+		s.connState = f
+	}
+}
+
+// WithBaseContext sets the function that creates the base context for connections accepted on a listener,
+// like http.Server.BaseContext. It must not return nil. The default is context.Background.
+// WithBaseContext는 http.Server.BaseContext처럼 리스너에서 수락된 연결의 기본 컨텍스트를 만드는 함수를 설정합니다.
+// nil을 반환해서는 안 되며, 기본값은 context.Background입니다.
+func WithBaseContext(f func(net.Listener) context.Context) Option {
+	return func(s *Server) {
+		s.baseContext = f
+	}
+}
+
+// WithConnContext sets a function that derives each connection's context, like http.Server.ConnContext.
+// Values it adds are visible through r.Context() in handlers. It must not return nil.
+// WithConnContext는 http.Server.ConnContext처럼 각 연결의 컨텍스트를 파생하는 함수를 설정합니다.
+// 여기서 추가한 값은 핸들러에서 r.Context()로 볼 수 있으며, nil을 반환해서는 안 됩니다.
+func WithConnContext(f func(ctx context.Context, conn netpoll.Connection) context.Context) Option {
+	return func(s *Server) {
+		s.connContext = f
+	}
+}
+
+// onConnState forwards a state change to the WithConnState hook.
+// onConnState는 상태 변경을 WithConnState 훅에 전달합니다.
+func (s *Server) onConnState(c *appcontext.Conn, state http.ConnState) {
+	s.connState(c.Netpoll(), state)
+}
+
+// newConn creates the connection state, wiring in the WithConnState hook if one is set.
+// newConn은 연결 상태를 생성하며, WithConnState 훅이 설정되어 있으면 연결합니다.
+func (s *Server) newConn(conn netpoll.Connection) *appcontext.Conn {
+	if s.connState == nil {
+		return appcontext.NewConn(conn, nil)
+	}
+	return appcontext.NewConn(conn, s.onConnState)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/netpoll"
+)
+
+var (
+	errMaxConns      = errors.New("server: too many connections")
+	errMaxConnsPerIP = errors.New("server: too many connections from one address")
+	errAcceptRate    = errors.New("server: accept rate exceeded")
+)
+
+// RejectPolicy decides what a connection refused by an admission limit receives.
+// RejectPolicy는 수락 제한에 걸린 연결이 받을 응답을 결정합니다.
+type RejectPolicy int
+
+const (
+	// RejectClose closes the connection without writing anything.
+	// RejectClose는 아무것도 쓰지 않고 연결을 닫습니다.
+	RejectClose RejectPolicy = iota
+	// RejectServiceUnavailable writes a minimal 503 response with Retry-After before closing.
+	// TLS connections are closed without a response, since no handshake has happened yet.
+	// RejectServiceUnavailable은 연결을 닫기 전에 Retry-After가 담긴 최소한의 503 응답을 보냅니다.
+	// TLS 연결은 아직 핸드셰이크 전이므로 응답 없이 닫습니다.
+	RejectServiceUnavailable
+)
+
+// WithMaxConns limits how many connections are open at once. Zero means no limit.
+// WithMaxConns는 동시에 열려 있을 수 있는 연결 수를 제한합니다. 0은 제한 없음을 의미합니다.
+func WithMaxConns(n int) Option {
+	return func(s *Server) {
+		s.maxConns = n
+	}
+}
+
+// WithMaxConnsPerIP limits how many connections a single client IP may hold. Zero means no limit.
+// Unix socket peers have no IP and are not counted.
+// WithMaxConnsPerIP는 하나의 클라이언트 IP가 보유할 수 있는 연결 수를 제한합니다. 0은 제한 없음을 의미합니다.
+// Unix 소켓 피어는 IP가 없으므로 집계하지 않습니다.
+func WithMaxConnsPerIP(n int) Option {
+	return func(s *Server) {
+		s.maxConnsPerIP = n
+	}
+}
+
+// WithAcceptRate limits accepted connections to perSecond on average, allowing bursts of burst.
+// WithAcceptRate는 수락하는 연결을 평균 초당 perSecond개로 제한하며, burst개까지의 순간 증가를 허용합니다.
+func WithAcceptRate(perSecond float64, burst int) Option {
+	return func(s *Server) {
+		if perSecond > 0 && burst > 0 {
+			s.acceptLimiter = newTokenBucket(perSecond, burst)
+		}
+	}
+}
+
+// WithRejectPolicy sets how connections over an admission limit are refused, and the
+// Retry-After sent with RejectServiceUnavailable. The default is RejectClose.
+// WithRejectPolicy는 수락 제한을 넘은 연결을 거부하는 방식과 RejectServiceUnavailable에서
+// 보낼 Retry-After를 설정합니다. 기본값은 RejectClose입니다.
+func WithRejectPolicy(policy RejectPolicy, retryAfter time.Duration) Option {
+	return func(s *Server) {
+		s.rejectPolicy = policy
+		s.retryAfter = retryAfter
+	}
+}
+
+// admit checks the admission limits for a connection from ip. s.mu must be held.
+// admit은 ip에서 온 연결에 대한 수락 제한을 확인합니다. s.mu를 잡은 상태여야 합니다.
+func (s *Server) admit(ip string) error {
+	if s.maxConns > 0 && len(s.conns) >= s.maxConns {
+		return errMaxConns
+	}
+	if s.maxConnsPerIP > 0 && ip != "" && s.connsPerIP[ip] >= s.maxConnsPerIP {
+		return errMaxConnsPerIP
+	}
+	// The rate is checked last so refused connections do not spend tokens.
+	// 거부된 연결이 토큰을 소모하지 않도록 속도는 마지막에 확인합니다.
+	if s.acceptLimiter != nil && !s.acceptLimiter.allow(time.Now()) {
+		return errAcceptRate
+	}
+	return nil
+}
+
+// reject refuses a connection in OnPrepare, before any request has been read.
+// reject는 요청을 읽기 전인 OnPrepare 단계에서 연결을 거부합니다.
+func (s *Server) reject(conn netpoll.Connection, err error) {
+	if err != http.ErrServerClosed {
+		s.log().Debug("connection rejected", "remote_addr", conn.RemoteAddr().String(), "reason", err)
+	}
+	if err != http.ErrServerClosed && s.rejectPolicy == RejectServiceUnavailable && s.tlsConfig == nil {
+		writer := conn.Writer()
+		writer.WriteString("HTTP/1.1 503 Service Unavailable\r\n")
+		if s.retryAfter > 0 {
+			secs := int64((s.retryAfter + time.Second - 1) / time.Second)
+			writer.WriteString("Retry-After: " + strconv.FormatInt(secs, 10) + "\r\n")
+		}
+		writer.WriteString("Content-Length: 0\r\nConnection: close\r\n\r\n")
+		_ = writer.Flush()
+	}
+	_ = conn.Close()
+}
+
+// remoteIP returns the peer IP used for per-IP limits, or "" for peers without one.
+// remoteIP는 IP별 제한에 사용할 피어 IP를 반환하며, IP가 없는 피어는 ""를 반환합니다.
+func remoteIP(conn netpoll.Connection) string {
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	return ""
+}
+
+// tokenBucket is a token-bucket rate limiter. It is not safe for concurrent use; Server guards it with mu.
+// tokenBucket은 토큰 버킷 속도 제한기입니다. 동시 사용에 안전하지 않으며, Server가 mu로 보호합니다.
+type tokenBucket struct {
+	rate   float64 // tokens per second. // 초당 토큰 수
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// allow refills the bucket for the time elapsed since the last call and takes one token if available.
+// allow는 마지막 호출 이후 경과한 시간만큼 버킷을 채우고, 토큰이 있으면 하나를 가져갑니다.
+func (b *tokenBucket) allow(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
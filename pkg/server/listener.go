@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/valyala/fasthttp/reuseport"
+)
+
+// WithUnixSocketMode sets the file permissions applied to a Unix socket after binding.
+// WithUnixSocketMode는 바인딩 후 Unix 소켓 파일에 적용할 권한을 설정합니다.
+func WithUnixSocketMode(mode os.FileMode) Option {
+	return func(s *Server) {
+		s.unixSocketMode = mode
+	}
+}
+
+// WithUnixSocketCleanup controls whether a stale socket file is removed before binding and
+// the socket file is unlinked when the listener closes. It is enabled by default.
+// WithUnixSocketCleanup은 바인딩 전에 남아 있는 소켓 파일을 제거하고 리스너가 닫힐 때
+// 소켓 파일을 삭제할지 여부를 제어합니다. 기본값은 활성화입니다.
+func WithUnixSocketCleanup(enabled bool) Option {
+	return func(s *Server) {
+		s.unixSocketCleanup = enabled
+	}
+}
+
+// listen creates the listener for network and addr.
+// listen은 network와 addr에 대한 리스너를 생성합니다.
+func (s *Server) listen(network, addr string) (net.Listener, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return reuseport.Listen(network, addr)
+	case "unix":
+		return s.listenUnix(addr)
+	default:
+		return nil, fmt.Errorf("server: unsupported network %q", network)
+	}
+}
+
+// listenUnix binds a Unix domain socket, applying the cleanup and permission options.
+// Abstract sockets ("@name") have no file, so both options are skipped for them.
+// listenUnix는 정리 및 권한 옵션을 적용하여 Unix 도메인 소켓을 바인딩합니다.
+// 추상 소켓("@name")은 파일이 없으므로 두 옵션 모두 건너뜁니다.
+func (s *Server) listenUnix(addr string) (net.Listener, error) {
+	abstract := strings.HasPrefix(addr, "@")
+	if !abstract && s.unixSocketCleanup {
+		removeStaleSocket(addr)
+	}
+
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: addr, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	if abstract {
+		return l, nil
+	}
+
+	l.SetUnlinkOnClose(s.unixSocketCleanup)
+	if s.unixSocketMode != 0 {
+		if err := os.Chmod(addr, s.unixSocketMode); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// removeStaleSocket deletes a socket file left behind by a previous process.
+// Files that are not sockets, or sockets something is still listening on, are left alone.
+// removeStaleSocket은 이전 프로세스가 남긴 소켓 파일을 삭제합니다.
+// 소켓이 아닌 파일이나 아직 수신 대기 중인 소켓은 그대로 둡니다.
+func removeStaleSocket(path string) {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return
+	}
+	_ = os.Remove(path)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// envPreforkChild carries the child index to processes started by prefork.
+	// envPreforkChild는 prefork로 시작된 프로세스에 자식 번호를 전달합니다.
+	envPreforkChild = "NETPOLL_PREFORK_CHILD"
+
+	// preforkRestartWindow bounds how many crashes are tolerated: more restarts than children
+	// within this window means the children cannot start, so the parent gives up.
+	// preforkRestartWindow는 허용할 크래시 수의 기준입니다. 이 시간 안에 자식 수보다 많이 재시작하면
+	// 자식이 시작될 수 없다는 뜻이므로 부모가 포기합니다.
+	preforkRestartWindow = time.Minute
+)
+
+var (
+	errPreforkNetwork  = errors.New("server: prefork requires a TCP network")
+	errPreforkRestarts = errors.New("server: prefork children keep crashing")
+	errPreforkStopping = errors.New("server: prefork is stopping")
+)
+
+// WithPrefork makes Serve start n child processes that each bind addr with SO_REUSEPORT and run
+// their own event loop, like fasthttp's prefork. n <= 0 uses GOMAXPROCS. The parent restarts
+// crashed children, forwards SIGHUP, SIGUSR1 and SIGUSR2, and turns SIGINT, SIGTERM or Shutdown
+// into a graceful shutdown of every child. Children re-execute the running binary; see
+// WithUpgradeCommand to change it.
+// WithPrefork는 fasthttp의 prefork처럼 Serve가 n개의 자식 프로세스를 시작하게 하며, 각 자식은
+// SO_REUSEPORT로 addr에 바인딩하고 자체 이벤트 루프를 실행합니다. n <= 0이면 GOMAXPROCS를 사용합니다.
+// 부모는 크래시한 자식을 재시작하고, SIGHUP, SIGUSR1, SIGUSR2를 전달하며, SIGINT, SIGTERM 또는 Shutdown을
+// 모든 자식의 우아한 종료로 바꿉니다. 자식은 실행 중인 바이너리를 다시 실행하며, WithUpgradeCommand로 바꿀 수 있습니다.
+func WithPrefork(n int) Option {
+	return func(s *Server) {
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		s.prefork = n
+	}
+}
+
+// WithPreforkCPUPinning pins child i to CPU i modulo the CPU count. It only has an effect on Linux.
+// WithPreforkCPUPinning은 자식 i를 CPU 수로 나눈 나머지 번호의 CPU에 고정합니다. Linux에서만 동작합니다.
+func WithPreforkCPUPinning(enabled bool) Option {
+	return func(s *Server) {
+		s.preforkPinCPU = enabled
+	}
+}
+
+// IsPreforkChild reports whether the process was started by a prefork parent.
+// IsPreforkChild는 프로세스가 prefork 부모에 의해 시작되었는지 보고합니다.
+func IsPreforkChild() bool {
+	return os.Getenv(envPreforkChild) != ""
+}
+
+// childExit reports that a prefork child ended. // childExit는 prefork 자식이 종료되었음을 알립니다.
+type childExit struct {
+	index int
+	err   error
+}
+
+// supervisor keeps the prefork children running until it is stopped.
+// supervisor는 중지될 때까지 prefork 자식들을 실행 상태로 유지합니다.
+type supervisor struct {
+	s     *Server
+	exits chan childExit
+	done  chan struct{}
+
+	mu       sync.Mutex
+	cmds     []*exec.Cmd // by child index, nil once exited. // 자식 번호별이며, 종료되면 nil입니다.
+	stopping bool
+}
+
+// servePrefork runs the parent side of prefork until every child has exited.
+// servePrefork는 모든 자식이 종료될 때까지 prefork의 부모 측을 실행합니다.
+func (s *Server) servePrefork(network string) error {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return errPreforkNetwork
+	}
+	// Children would fail the same way, so report bad settings before starting them.
+	// 자식도 같은 이유로 실패할 것이므로 시작 전에 잘못된 설정을 보고합니다.
+	if s.tuningErr != nil {
+		return s.tuningErr
+	}
+
+	sv := &supervisor{
+		s:     s,
+		exits: make(chan childExit, s.prefork),
+		done:  make(chan struct{}),
+		cmds:  make([]*exec.Cmd, s.prefork),
+	}
+	defer close(sv.done)
+
+	s.mu.Lock()
+	if s.inShutdown {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.supervisor = sv
+	s.mu.Unlock()
+
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigs)
+
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+		sv.stop(syscall.SIGTERM)
+	}
+
+	running := 0
+	for i := range s.prefork {
+		if err := sv.start(i); err != nil {
+			fail(err)
+			break
+		}
+		running++
+	}
+
+	var restarts []time.Time
+	for running > 0 {
+		select {
+		case sig := <-sigs:
+			if sig == syscall.SIGINT || sig == syscall.SIGTERM {
+				sv.stop(sig)
+			} else {
+				sv.signal(sig)
+			}
+		case e := <-sv.exits:
+			running--
+			if sv.isStopping() {
+				continue
+			}
+			s.log().Warn("prefork child exited, restarting", "child", e.index, "error", e.err)
+
+			now := time.Now()
+			for len(restarts) > 0 && now.Sub(restarts[0]) > preforkRestartWindow {
+				restarts = restarts[1:]
+			}
+			if restarts = append(restarts, now); len(restarts) > s.prefork {
+				fail(errPreforkRestarts)
+				continue
+			}
+			if err := sv.start(e.index); err != nil {
+				if err != errPreforkStopping {
+					fail(err)
+				}
+				continue
+			}
+			running++
+		}
+	}
+	return firstErr
+}
+
+// start launches child i unless the supervisor is stopping.
+// start는 supervisor가 중지 중이 아니라면 자식 i를 시작합니다.
+func (sv *supervisor) start(i int) error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	if sv.stopping {
+		return errPreforkStopping
+	}
+
+	path, args, err := sv.s.childCommand()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), envPreforkChild+"="+strconv.Itoa(i))
+	cmd.SysProcAttr = childSysProcAttr()
+
+	cpu := -1
+	if sv.s.preforkPinCPU {
+		cpu = i % runtime.NumCPU()
+	}
+	if err := startPinned(cmd, cpu); err != nil {
+		return err
+	}
+	sv.cmds[i] = cmd
+
+	go func() {
+		err := cmd.Wait()
+		sv.mu.Lock()
+		sv.cmds[i] = nil
+		sv.mu.Unlock()
+		sv.exits <- childExit{index: i, err: err}
+	}()
+	return nil
+}
+
+// signal sends sig to every running child.
+// signal은 실행 중인 모든 자식에게 sig를 보냅니다.
+func (sv *supervisor) signal(sig os.Signal) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	for _, cmd := range sv.cmds {
+		if cmd != nil {
+			_ = cmd.Process.Signal(sig)
+		}
+	}
+}
+
+// stop disables restarts and sends sig to every running child.
+// stop은 재시작을 중단하고 실행 중인 모든 자식에게 sig를 보냅니다.
+func (sv *supervisor) stop(sig os.Signal) {
+	sv.mu.Lock()
+	sv.stopping = true
+	sv.mu.Unlock()
+	sv.signal(sig)
+}
+
+func (sv *supervisor) isStopping() bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.stopping
+}
+
+// shutdown asks every child to shut down gracefully and kills the ones still running when ctx expires.
+// shutdown은 모든 자식에게 우아한 종료를 요청하고, ctx가 만료될 때까지 실행 중인 자식은 강제 종료합니다.
+func (sv *supervisor) shutdown(ctx context.Context) error {
+	sv.stop(syscall.SIGTERM)
+	select {
+	case <-sv.done:
+		return nil
+	case <-ctx.Done():
+		sv.signal(syscall.SIGKILL)
+		return ctx.Err()
+	}
+}
+
+// shutdownOnSignal makes a prefork child drain through Shutdown when its parent asks it to stop.
+// shutdownOnSignal은 부모가 중지를 요청하면 prefork 자식이 Shutdown으로 드레이닝하도록 합니다.
+func (s *Server) shutdownOnSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		signal.Stop(sigs)
+		_ = s.Shutdown(context.Background())
+	}()
+}
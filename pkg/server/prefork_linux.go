@@ -0,0 +1,40 @@
+package server
+
+import (
+	"os/exec"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// childSysProcAttr makes children receive SIGTERM if the parent dies without stopping them.
+// childSysProcAttr는 부모가 자식을 중지하지 않고 죽으면 자식이 SIGTERM을 받도록 합니다.
+func childSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+}
+
+// startPinned starts cmd restricted to cpu, or unrestricted when cpu is negative.
+// A forked child inherits the affinity of the forking thread, so the mask is set on a locked
+// thread around Start and restored afterwards.
+// startPinned는 cmd를 cpu로 제한하여 시작하며, cpu가 음수이면 제한하지 않습니다.
+// fork된 자식은 fork한 스레드의 affinity를 물려받으므로, Start 전후로 고정된 스레드에 마스크를 설정하고 복원합니다.
+func startPinned(cmd *exec.Cmd, cpu int) error {
+	if cpu < 0 {
+		return cmd.Start()
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var old, set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &old); err != nil {
+		return err
+	}
+	set.Set(cpu)
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		return err
+	}
+	defer unix.SchedSetaffinity(0, &old) // nolint:errcheck
+	return cmd.Start()
+}
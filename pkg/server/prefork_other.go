@@ -0,0 +1,18 @@
+//go:build !linux
+
+package server
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func childSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// startPinned starts cmd; CPU pinning is only supported on Linux.
+// startPinned는 cmd를 시작합니다. CPU 고정은 Linux에서만 지원됩니다.
+func startPinned(cmd *exec.Cmd, cpu int) error {
+	return cmd.Start()
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/netip"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/proxyproto"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// WithProxyProtocol accepts a HAProxy PROXY protocol v1 or v2 header at the start of each connection
+// and reports the relayed client address in req.RemoteAddr. The header is optional, so direct clients
+// keep working. When trusted prefixes are given, only peers inside them may send a header; other peers
+// are served as if the option were off. Admission limits still see the proxy's address.
+// WithProxyProtocol은 각 연결 시작 부분의 HAProxy PROXY 프로토콜 v1 또는 v2 헤더를 받아들이고,
+// 전달된 클라이언트 주소를 req.RemoteAddr에 기록합니다. 헤더는 선택 사항이므로 직접 접속하는 클라이언트도
+// 계속 동작합니다. trusted 대역이 주어지면 그 안의 피어만 헤더를 보낼 수 있으며, 다른 피어는 옵션이 꺼진
+// 것처럼 처리됩니다. 수락 제한은 여전히 프록시의 주소를 기준으로 합니다.
+func WithProxyProtocol(trusted ...netip.Prefix) Option {
+	return func(s *Server) {
+		s.proxyProtocol = true
+		s.proxyTrusted = trusted
+	}
+}
+
+type ctxProxyKeyStruct struct{}
+
+var ctxProxyKey = ctxProxyKeyStruct{}
+
+// proxyState marks a connection expected to start with a PROXY header.
+// OnRequest calls for one connection never overlap, so done needs no locking.
+// proxyState는 PROXY 헤더로 시작할 것으로 예상되는 연결을 표시합니다.
+// 한 연결에 대한 OnRequest 호출은 겹치지 않으므로 done에는 잠금이 필요 없습니다.
+type proxyState struct {
+	done bool
+}
+
+// trustsProxy reports whether conn may send a PROXY header.
+// Peers without an IP address are only trusted when no prefixes are configured.
+// trustsProxy는 conn이 PROXY 헤더를 보낼 수 있는지 보고합니다.
+// IP 주소가 없는 피어는 대역이 설정되지 않은 경우에만 신뢰합니다.
+func (s *Server) trustsProxy(conn netpoll.Connection) bool {
+	if len(s.proxyTrusted) == 0 {
+		return true
+	}
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(addr.IP)
+	if !ok {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, p := range s.proxyTrusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// readProxyHeader consumes the PROXY header, if any, the first time a trusted connection has data.
+// readProxyHeader는 신뢰하는 연결에 처음 데이터가 도착했을 때 PROXY 헤더가 있으면 소비합니다.
+func readProxyHeader(ctx context.Context, conn netpoll.Connection) error {
+	ps, ok := ctx.Value(ctxProxyKey).(*proxyState)
+	if !ok || ps.done {
+		return nil
+	}
+	ps.done = true
+
+	reader := conn.Reader()
+	h, err := proxyproto.Read(reader)
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		return nil
+	}
+	_ = reader.Release()
+	if c := appcontext.ConnFromContext(ctx); c != nil {
+		c.SetProxyHeader(h)
+	}
+	return nil
+}
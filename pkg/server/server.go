@@ -2,31 +2,74 @@ package server
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
 	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/h2"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/metrics"
 
 	"github.com/cloudwego/netpoll"
-	"github.com/valyala/fasthttp/reuseport"
 )
 
 // Server is the top-level structure for the netpoll server.
 // Server는 netpoll 서버의 최상위 구조체입니다.
 type Server struct {
-	Engine           *engine.Engine
-	eventLoop        netpoll.EventLoop
-	keepAliveTimeout time.Duration
-	readTimeout      time.Duration
-	writeTimeout     time.Duration
+	Engine            *engine.Engine
+	eventLoop         netpoll.EventLoop
+	keepAliveTimeout  time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	tlsConfig         *tls.Config
+	unixSocketMode    os.FileMode
+	unixSocketCleanup bool
+	upgradePath       string
+	upgradeArgs       []string
+	maxConns          int
+	maxConnsPerIP     int
+	acceptLimiter     *tokenBucket
+	rejectPolicy      RejectPolicy
+	retryAfter        time.Duration
+	proxyProtocol     bool
+	proxyTrusted      []netip.Prefix
+	connState         func(netpoll.Connection, http.ConnState)
+	baseContext       func(net.Listener) context.Context
+	connContext       func(context.Context, netpoll.Connection) context.Context
+	prefork           int
+	preforkPinCPU     bool
+	tuning            netpollTuning
+	tuningErr         error
+	metrics           *metrics.Metrics
+	logger            *slog.Logger
+
+	// mu guards the fields below. // mu는 아래 필드들을 보호합니다.
+	mu           sync.Mutex
+	listener     net.Listener
+	listenerName string
+	supervisor   *supervisor
+	conns        map[*appcontext.Conn]struct{}
+	connsPerIP   map[string]int
+	inShutdown   bool
+	onShutdown   []func()
+	tuningHeld   int
 }
 
 // Option is a function type for configuring the Server.
 // Option은 서버 설정을 위한 함수 타입입니다.
 type Option func(*Server)
 
-// WithReadTimeout sets the read timeout.
-// WithReadTimeout은 읽기 타임아웃을 설정합니다.
+// WithReadTimeout sets the read timeout, which bounds every single wait for data on a connection.
+// For per-request limits see engine.WithIdleTimeout, WithReadHeaderTimeout and WithBodyReadTimeout.
+// WithReadTimeout은 연결에서 데이터를 기다리는 매 대기 시간을 제한하는 읽기 타임아웃을 설정합니다.
+// 요청 단위 제한은 engine.WithIdleTimeout, WithReadHeaderTimeout, WithBodyReadTimeout을 참고하세요.
 func WithReadTimeout(d time.Duration) Option {
 	return func(s *Server) {
 		s.readTimeout = d
@@ -49,6 +92,27 @@ func WithKeepAliveTimeout(d time.Duration) Option {
 	}
 }
 
+// WithMetrics counts accepted, active and closed connections in m.
+// Pass the same Metrics to engine.WithMetrics to record requests as well.
+// WithMetrics는 수락된 연결, 활성 연결, 닫힌 연결 수를 m에 기록합니다.
+// 요청도 기록하려면 같은 Metrics를 engine.WithMetrics에 전달하세요.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// WithLogger sets the logger for server lifecycle events and connection failures such as
+// TLS handshake and PROXY header errors. The default is slog.Default(). The Engine has its own
+// logger; see engine.WithLogger.
+// WithLogger는 서버 수명 주기 이벤트와 TLS 핸드셰이크, PROXY 헤더 오류 같은 연결 실패를 기록할 로거를
+// 설정합니다. 기본값은 slog.Default()입니다. Engine은 별도의 로거를 가지며, engine.WithLogger를 참고하세요.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
 // NewServer creates a new Server.
 // NewServer는 새로운 Server를 생성합니다.
 func NewServer(e *engine.Engine, opts ...Option) *Server {
@@ -57,6 +121,9 @@ func NewServer(e *engine.Engine, opts ...Option) *Server {
 		keepAliveTimeout: 30 * time.Second, // Default // 기본값
 		readTimeout:      10 * time.Second,
 		writeTimeout:     10 * time.Second,
+
+		unixSocketCleanup: true,
+		tuning:            netpollTuning{loadBalance: -1},
 	}
 
 	for _, opt := range opts {
@@ -71,17 +138,56 @@ func NewServer(e *engine.Engine, opts ...Option) *Server {
 // Serve는 netpoll 이벤트 루프를 시작하여 들어오는 요청을 처리합니다.
 // SO_REUSEPORT를 사용하여 다중 프로세스/스레드 바인딩 성능을 높입니다.
 func (s *Server) Serve(addr string) error {
-	l, err := reuseport.Listen("tcp", addr)
+	return s.ServeNetwork("tcp", addr)
+}
+
+// ServeNetwork listens on network ("tcp", "tcp4", "tcp6" or "unix") and serves incoming requests.
+// TCP networks bind with SO_REUSEPORT; a unix addr starting with "@" is an abstract socket on Linux.
+// ServeNetwork는 network("tcp", "tcp4", "tcp6" 또는 "unix")에서 수신 대기하며 요청을 처리합니다.
+// TCP 네트워크는 SO_REUSEPORT로 바인딩하며, "@"로 시작하는 unix addr은 Linux의 추상 소켓입니다.
+func (s *Server) ServeNetwork(network, addr string) error {
+	if s.prefork > 0 {
+		if !IsPreforkChild() {
+			return s.servePrefork(network)
+		}
+		s.shutdownOnSignal()
+	}
+
+	l, err := s.listen(network, addr)
 	if err != nil {
 		return err
 	}
+	return s.ServeListener(l)
+}
 
+// ServeListener serves incoming requests on a listener prepared by the caller.
+// netpoll can only poll *net.TCPListener and *net.UnixListener values.
+// ServeListener는 호출자가 준비한 리스너에서 요청을 처리합니다.
+// netpoll은 *net.TCPListener와 *net.UnixListener만 폴링할 수 있습니다.
+func (s *Server) ServeListener(l net.Listener) error {
 	listener, err := netpoll.ConvertListener(l)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Server listening on %s", addr)
+	s.log().Info("server listening", "network", listener.Addr().Network(), "addr", listener.Addr().String())
+
+	// Advertise HTTP/2 through ALPN when the Engine supports it.
+	// Engine이 지원하면 ALPN으로 HTTP/2를 알립니다.
+	if s.tlsConfig != nil && s.Engine.HTTP2Enabled() && !slices.Contains(s.tlsConfig.NextProtos, h2.NextProtoTLS) {
+		s.tlsConfig.NextProtos = append([]string{h2.NextProtoTLS}, s.tlsConfig.NextProtos...)
+		if !slices.Contains(s.tlsConfig.NextProtos, "http/1.1") {
+			s.tlsConfig.NextProtos = append(s.tlsConfig.NextProtos, "http/1.1")
+		}
+	}
+
+	baseCtx := context.Background()
+	if s.baseContext != nil {
+		baseCtx = s.baseContext(l)
+		if baseCtx == nil {
+			panic("server: BaseContext returned a nil context")
+		}
+	}
 
 	opts := []netpoll.Option{
 		netpoll.WithIdleTimeout(s.keepAliveTimeout),
@@ -91,8 +197,46 @@ func (s *Server) Serve(addr string) error {
 				conn.SetWriteTimeout(s.writeTimeout)
 			}
 
-			ctx := context.Background()
-			ctx = cancelContext(ctx) // Creates and registers a cancellable context. // 취소 가능한 컨텍스트 생성 및 등록
+			c := s.newConn(conn)
+			if err := s.trackConn(c); err != nil {
+				// Over a limit, or shutting down and refusing connections accepted before the listener closed.
+				// 제한을 넘었거나, 종료 중이어서 리스너가 닫히기 전에 수락된 연결을 거부합니다.
+				s.reject(conn, err)
+				return context.Background()
+			}
+			if s.metrics != nil {
+				s.metrics.ConnAccepted()
+			}
+			if s.connState != nil {
+				s.connState(conn, http.StateNew)
+			}
+
+			ctx := cancelContext(baseCtx) // Creates and registers a cancellable context. // 취소 가능한 컨텍스트 생성 및 등록
+			ctx = appcontext.WithConn(ctx, c)
+			if s.connContext != nil {
+				if ctx = s.connContext(ctx, conn); ctx == nil {
+					panic("server: ConnContext returned nil")
+				}
+			}
+			// OnDisconnect only fires when the peer hangs up, so untrack and cancel on every close.
+			// OnDisconnect는 피어가 끊을 때만 호출되므로 모든 종료 시점에 추적을 해제하고 컨텍스트를 취소합니다.
+			cancel, _ := ctx.Value(ctxCancelKey).(context.CancelFunc)
+			conn.AddCloseCallback(func(netpoll.Connection) error {
+				c.SetState(http.StateClosed)
+				s.untrackConn(c)
+				if s.metrics != nil {
+					s.metrics.ConnClosed()
+				}
+				cancel()
+				return nil
+			})
+			if s.proxyProtocol && s.trustsProxy(conn) {
+				ctx = context.WithValue(ctx, ctxProxyKey, new(proxyState))
+			}
+			if s.tlsConfig != nil {
+				// The TLS session lives as long as the connection. // TLS 세션은 연결과 수명을 같이합니다.
+				ctx = context.WithValue(ctx, ctxTLSConnKey, newTLSConn(conn, s.tlsConfig))
+			}
 			return ctx
 		}),
 		netpoll.WithOnDisconnect(func(ctx context.Context, connection netpoll.Connection) {
@@ -103,24 +247,83 @@ func (s *Server) Serve(addr string) error {
 		}),
 	}
 
+	// netpoll reads its global settings when the event loop starts polling, so apply them first.
+	// netpoll은 이벤트 루프가 폴링을 시작할 때 전역 설정을 읽으므로 먼저 적용합니다.
+	if err := s.applyTuning(); err != nil {
+		listener.Close()
+		return err
+	}
+
 	// OnRequest callback invokes the Engine's ServeConn method.
 	// OnRequest 콜백은 Engine의 ServeConn 메서드를 호출합니다.
-	eventLoop, err := netpoll.NewEventLoop(s.Engine.ServeConn, opts...)
+	eventLoop, err := netpoll.NewEventLoop(s.onRequest, opts...)
 	if err != nil {
+		s.releaseTuning()
 		return err
 	}
+
+	s.mu.Lock()
+	if s.inShutdown {
+		s.mu.Unlock()
+		s.releaseTuning()
+		listener.Close()
+		return http.ErrServerClosed
+	}
 	s.eventLoop = eventLoop
+	s.listener = l
+	s.mu.Unlock()
 
 	return eventLoop.Serve(listener)
 }
 
-// Shutdown gracefully shuts down the server.
-// Shutdown은 서버를 우아하게 종료합니다.
-func (s *Server) Shutdown(ctx context.Context) error {
-	if s.eventLoop == nil {
-		return nil
+// onRequest reads the PROXY header and swaps in the TLS connection, completing the handshake on first use,
+// before handing off to the Engine.
+// onRequest는 Engine에 넘기기 전에 PROXY 헤더를 읽고 TLS 연결로 교체하며, 처음 사용할 때 핸드셰이크를 완료합니다.
+func (s *Server) onRequest(ctx context.Context, conn netpoll.Connection) error {
+	// The PROXY header precedes everything else, including the TLS handshake.
+	// PROXY 헤더는 TLS 핸드셰이크를 포함한 모든 것보다 앞에 옵니다.
+	if err := readProxyHeader(ctx, conn); err != nil {
+		s.connLogger(ctx, conn).Warn("invalid PROXY header", "error", err)
+		_ = conn.Close()
+		return err
 	}
-	return s.eventLoop.Shutdown(ctx)
+	if tc, ok := ctx.Value(ctxTLSConnKey).(*tlsConn); ok {
+		if err := tc.Handshake(ctx); err != nil {
+			s.connLogger(ctx, conn).Debug("TLS handshake failed", "error", err)
+			_ = conn.Close()
+			return err
+		}
+		return s.Engine.ServeConn(ctx, tc)
+	}
+	return s.Engine.ServeConn(ctx, conn)
+}
+
+// log returns the configured logger, falling back to slog.Default() at call time.
+// log는 설정된 로거를 반환하며, 없으면 호출 시점의 slog.Default()를 사용합니다.
+func (s *Server) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	return slog.Default()
+}
+
+// connLogger returns the logger with the connection's remote address and id attached.
+// connLogger는 연결의 원격 주소와 ID가 추가된 로거를 반환합니다.
+func (s *Server) connLogger(ctx context.Context, conn netpoll.Connection) *slog.Logger {
+	l := s.log().With("remote_addr", conn.RemoteAddr().String())
+	if c := appcontext.ConnFromContext(ctx); c != nil {
+		l = l.With("conn_id", c.ID())
+	}
+	return l
+}
+
+// Shutdown gracefully shuts down the server, draining in-flight requests until ctx expires.
+// See GracefulShutdown for the details and a report of force-closed connections.
+// Shutdown은 ctx가 만료될 때까지 진행 중인 요청을 드레이닝하며 서버를 우아하게 종료합니다.
+// 자세한 동작과 강제로 닫힌 연결의 보고서는 GracefulShutdown을 참고하세요.
+func (s *Server) Shutdown(ctx context.Context) error {
+	_, err := s.GracefulShutdown(ctx)
+	return err
 }
 
 type ctxCancelKeyStruct struct{}
@@ -131,4 +334,4 @@ func cancelContext(ctx context.Context) context.Context {
 	ctx, cancel := context.WithCancel(ctx)
 	ctx = context.WithValue(ctx, ctxCancelKey, cancel)
 	return ctx
-}
\ No newline at end of file
+}
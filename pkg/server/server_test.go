@@ -0,0 +1,1622 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/accesslog"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/metrics"
+
+	"github.com/cloudwego/netpoll"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// freeAddr reserves an ephemeral loopback port and releases it for the server under test.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// waitDial blocks until addr accepts connections.
+func waitDial(t *testing.T, network, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c, err := net.Dial(network, addr)
+		if err == nil {
+			c.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server at %s did not come up", addr)
+}
+
+func TestServeTLS(t *testing.T) {
+	// Borrow httptest's self-signed certificate and a client that trusts it.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	client := ts.Client()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || !r.TLS.HandshakeComplete {
+			t.Errorf("expected completed TLS state on request")
+		}
+		if r.URL.Scheme != "https" {
+			t.Errorf("expected https scheme, got %q", r.URL.Scheme)
+		}
+		io.WriteString(w, "secure")
+	})
+
+	srv := NewServer(engine.NewEngine(handler), WithTLSConfig(&tls.Config{
+		Certificates: ts.TLS.Certificates,
+	}))
+	addr := freeAddr(t)
+	go srv.ServeTLS(addr, "", "")
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	// Two requests exercise the keep-alive loop over the same TLS session.
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get("https://" + addr + "/")
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "secure" {
+			t.Errorf("request %d: unexpected body %q", i, body)
+		}
+	}
+	client.CloseIdleConnections()
+}
+
+// echoHandler replies with the protocol, the request body length and a large payload
+// so that responses span several DATA frames.
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	n, _ := io.Copy(io.Discard, r.Body)
+	w.Header().Set("X-Proto", r.Proto)
+	w.Header().Set("X-Body-Len", strconv.FormatInt(n, 10))
+	w.Write(bytes.Repeat([]byte("x"), 200<<10))
+}
+
+func checkEcho(t *testing.T, resp *http.Response, wantBody int) {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body failed: %v", err)
+	}
+	if resp.ProtoMajor != 2 || resp.Header.Get("X-Proto") != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2, got client %s server %s", resp.Proto, resp.Header.Get("X-Proto"))
+	}
+	if got := resp.Header.Get("X-Body-Len"); got != strconv.Itoa(wantBody) {
+		t.Errorf("server saw %s request bytes, want %d", got, wantBody)
+	}
+	if len(body) != 200<<10 {
+		t.Errorf("expected %d response bytes, got %d", 200<<10, len(body))
+	}
+}
+
+func TestHTTP2_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(echoHandler), engine.WithHTTP2()),
+		WithTLSConfig(&tls.Config{Certificates: ts.TLS.Certificates}))
+	addr := freeAddr(t)
+	go srv.ServeTLS(addr, "", "")
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	tr := &http.Transport{
+		TLSClientConfig:   ts.Client().Transport.(*http.Transport).TLSClientConfig,
+		ForceAttemptHTTP2: true,
+	}
+	defer tr.CloseIdleConnections()
+	client := &http.Client{Transport: tr}
+
+	// Concurrent requests share one connection as separate streams.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Post("https://"+addr+"/", "text/plain", strings.NewReader(strings.Repeat("y", 100<<10)))
+			if err != nil {
+				t.Errorf("request failed: %v", err)
+				return
+			}
+			checkEcho(t, resp, 100<<10)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHTTP2_PriorKnowledge(t *testing.T) {
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(echoHandler), engine.WithHTTP2()))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	tr := &http.Transport{Protocols: protocols}
+	defer tr.CloseIdleConnections()
+	client := &http.Client{Transport: tr}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post("http://"+addr+"/", "text/plain", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		checkEcho(t, resp, 5)
+	}
+}
+
+func TestHTTP2_Upgrade(t *testing.T) {
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(echoHandler), engine.WithHTTP2()))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "POST / HTTP/1.1\r\nHost: "+addr+"\r\nConnection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAP__\r\nContent-Length: 3\r\n\r\nabc")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read 101 failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+
+	io.WriteString(conn, http2.ClientPreface)
+	fr := http2.NewFramer(conn, br)
+	fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	fr.WriteSettings()
+	fr.WriteWindowUpdate(0, 1<<30)
+	fr.WriteWindowUpdate(1, 1<<30)
+
+	var status string
+	var got int
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			t.Fatalf("read frame failed: %v", err)
+		}
+		switch f := f.(type) {
+		case *http2.MetaHeadersFrame:
+			status = f.PseudoValue("status")
+			for _, hf := range f.RegularFields() {
+				if hf.Name == "x-body-len" && hf.Value != "3" {
+					t.Errorf("upgrade body length %s, want 3", hf.Value)
+				}
+			}
+		case *http2.DataFrame:
+			got += len(f.Data())
+			if f.StreamEnded() {
+				if status != "200" || got != 200<<10 {
+					t.Errorf("stream 1: status %s, %d bytes", status, got)
+				}
+				return
+			}
+		}
+	}
+}
+
+// unixClient returns an http.Client that dials the given Unix socket for every request.
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestServeNetwork_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "http.sock")
+
+	// A stale socket file from a previous run must not prevent binding.
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.RemoteAddr)
+	})
+	srv := NewServer(engine.NewEngine(handler), WithUnixSocketMode(0o600))
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeNetwork("unix", path) }()
+	waitDial(t, "unix", path)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v", fi.Mode().Perm())
+	}
+
+	client := unixClient(path)
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "@" {
+		t.Errorf("expected RemoteAddr \"@\", got %q", body)
+	}
+	client.CloseIdleConnections()
+
+	srv.Shutdown(context.Background())
+	<-done
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected socket file to be removed, stat err: %v", err)
+	}
+}
+
+func TestServeListener_Abstract(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract Unix sockets are Linux-only")
+	}
+	name := "@http-over-netpoll-test-" + strconv.Itoa(os.Getpid())
+	l, err := net.Listen("unix", name)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})))
+	go srv.ServeListener(l)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "unix", name)
+
+	client := unixClient(name)
+	defer client.CloseIdleConnections()
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			close(started)
+			<-release
+		case "/stream":
+			// Long-lived handlers wrap up once the connection starts draining.
+			<-appcontext.ConnFromContext(r.Context()).DrainNotify()
+		}
+		io.WriteString(w, "done")
+	})
+	srv := NewServer(engine.NewEngine(handler))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	waitDial(t, "tcp", addr)
+
+	// An idle keep-alive connection.
+	idle := &http.Client{Transport: &http.Transport{}}
+	resp, err := idle.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("idle request failed: %v", err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := (&http.Client{Transport: &http.Transport{}}).Get("http://" + addr + "/slow")
+		slow <- result{resp, err}
+	}()
+	stream := make(chan result, 1)
+	go func() {
+		resp, err := (&http.Client{Transport: &http.Transport{}}).Get("http://" + addr + "/stream")
+		stream <- result{resp, err}
+	}()
+	<-started
+	time.Sleep(50 * time.Millisecond)
+
+	reportCh := make(chan ShutdownReport, 1)
+	go func() {
+		report, err := srv.GracefulShutdown(context.Background())
+		if err != nil {
+			t.Errorf("shutdown failed: %v", err)
+		}
+		reportCh <- report
+	}()
+
+	r := <-stream
+	if r.err != nil {
+		t.Fatalf("stream request failed: %v", r.err)
+	}
+	r.resp.Body.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	r = <-slow
+	if r.err != nil {
+		t.Fatalf("slow request failed: %v", r.err)
+	}
+	body, _ := io.ReadAll(r.resp.Body)
+	r.resp.Body.Close()
+	if string(body) != "done" || !r.resp.Close {
+		t.Errorf("expected completed response with Connection: close, got %q close=%v", body, r.resp.Close)
+	}
+
+	report := <-reportCh
+	if report.IdleClosed != 1 || report.Drained != 2 || len(report.ForceClosed) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestGracefulShutdown_Deadline(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	srv := NewServer(engine.NewEngine(handler))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	waitDial(t, "tcp", addr)
+
+	go (&http.Client{Transport: &http.Transport{}}).Get("http://" + addr + "/")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	report, err := srv.GracefulShutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	if len(report.ForceClosed) != 1 || report.ForceClosed[0].State != http.StateActive {
+		t.Errorf("expected one active connection force-closed, got %+v", report.ForceClosed)
+	}
+}
+
+// pidHandler answers with the serving process ID so tests can tell processes apart.
+func pidHandler(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, strconv.Itoa(os.Getpid()))
+}
+
+// TestUpgradeChild is the new process started by TestUpgrade from the test binary.
+func TestUpgradeChild(t *testing.T) {
+	if os.Getenv("UPGRADE_TEST_CHILD") != "1" {
+		t.Skip("helper process for TestUpgrade")
+	}
+	time.AfterFunc(10*time.Second, func() { os.Exit(1) })
+
+	ls, err := InheritedListeners()
+	if err != nil || len(ls["unknown"]) != 1 {
+		t.Fatalf("expected one inherited listener, got %v, %v", ls, err)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pidHandler(w, r)
+		if r.URL.Path == "/exit" {
+			time.AfterFunc(50*time.Millisecond, func() { os.Exit(0) })
+		}
+	})
+	srv := NewServer(engine.NewEngine(handler))
+	go srv.ServeInherited("")
+	if err := Ready(); err != nil {
+		t.Fatalf("ready failed: %v", err)
+	}
+	select {}
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Setenv("UPGRADE_TEST_CHILD", "1")
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)),
+		WithUpgradeCommand(os.Args[0], "-test.run=^TestUpgradeChild$"))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	waitDial(t, "tcp", addr)
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(path string) string {
+		t.Helper()
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	self := strconv.Itoa(os.Getpid())
+	if got := get("/"); got != self {
+		t.Fatalf("expected the parent to answer, got pid %s", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Upgrade(ctx); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+
+	// The parent has stopped accepting, so the same socket is now served by the child.
+	if got := get("/"); got == self || got == "" {
+		t.Errorf("expected the new process to answer, got pid %q", got)
+	}
+	get("/exit")
+}
+
+// readRejection reads whatever a refused connection receives before the server closes it.
+func readRejection(t *testing.T, addr string) string {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	b, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatalf("expected the server to close the connection: %v", err)
+	}
+	return string(b)
+}
+
+func TestMaxConns(t *testing.T) {
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)),
+		WithMaxConns(1), WithRejectPolicy(RejectServiceUnavailable, 1500*time.Millisecond))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+	time.Sleep(50 * time.Millisecond) // Let the probe connection be released.
+
+	held, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	resp := readRejection(t, addr)
+	if !strings.HasPrefix(resp, "HTTP/1.1 503 ") || !strings.Contains(resp, "Retry-After: 2\r\n") {
+		t.Errorf("expected 503 with Retry-After, got %q", resp)
+	}
+
+	// Closing the held connection frees the slot.
+	held.Close()
+	time.Sleep(50 * time.Millisecond)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	r, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("request after release failed: %v", err)
+	}
+	r.Body.Close()
+}
+
+func TestMaxConnsPerIP(t *testing.T) {
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)), WithMaxConnsPerIP(2))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+	time.Sleep(50 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer c.Close()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// The default policy closes without a response.
+	if resp := readRejection(t, addr); resp != "" {
+		t.Errorf("expected an empty close, got %q", resp)
+	}
+}
+
+func TestAcceptRate(t *testing.T) {
+	// The burst covers waitDial's probe and one real connection; the refill is far too slow to matter.
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)),
+		WithAcceptRate(0.001, 2), WithRejectPolicy(RejectServiceUnavailable, 0))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	r, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("request within burst failed: %v", err)
+	}
+	r.Body.Close()
+
+	resp := readRejection(t, addr)
+	if !strings.HasPrefix(resp, "HTTP/1.1 503 ") || strings.Contains(resp, "Retry-After") {
+		t.Errorf("expected 503 without Retry-After, got %q", resp)
+	}
+}
+
+// rawGet sends prefix followed by a GET request on a fresh connection and returns the response body.
+func rawGet(t *testing.T, addr string, prefix []byte) string {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer c.Close()
+	c.Write(append(prefix, "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n"...))
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatalf("read response failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestProxyProtocol(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.RemoteAddr)
+	})
+	v2 := []byte("\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c")
+	v2 = append(v2, 203, 0, 113, 9, 198, 51, 100, 1, 0x1f, 0x90, 0x01, 0xbb)
+
+	t.Run("trusted", func(t *testing.T) {
+		srv := NewServer(engine.NewEngine(handler), WithProxyProtocol())
+		addr := freeAddr(t)
+		go srv.Serve(addr)
+		defer srv.Shutdown(context.Background())
+		waitDial(t, "tcp", addr)
+
+		if got := rawGet(t, addr, []byte("PROXY TCP4 203.0.113.7 198.51.100.1 51234 443\r\n")); got != "203.0.113.7:51234" {
+			t.Errorf("v1: RemoteAddr = %q", got)
+		}
+		if got := rawGet(t, addr, v2); got != "203.0.113.9:8080" {
+			t.Errorf("v2: RemoteAddr = %q", got)
+		}
+		// The header is optional.
+		if got := rawGet(t, addr, nil); !strings.HasPrefix(got, "127.0.0.1:") {
+			t.Errorf("direct: RemoteAddr = %q", got)
+		}
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		srv := NewServer(engine.NewEngine(handler), WithProxyProtocol(netip.MustParsePrefix("10.0.0.0/8")))
+		addr := freeAddr(t)
+		go srv.Serve(addr)
+		defer srv.Shutdown(context.Background())
+		waitDial(t, "tcp", addr)
+
+		if got := rawGet(t, addr, nil); !strings.HasPrefix(got, "127.0.0.1:") {
+			t.Errorf("RemoteAddr = %q", got)
+		}
+	})
+}
+
+func TestConnStateHooks(t *testing.T) {
+	type ctxKey struct{}
+	var (
+		mu     sync.Mutex
+		states []http.ConnState
+		base   net.Listener
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, _ := r.Context().Value(ctxKey{}).(string); v != "per-conn" {
+			t.Errorf("ConnContext value missing, got %q", v)
+		}
+		if r.URL.Path == "/hijack" {
+			c, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("hijack failed: %v", err)
+				return
+			}
+			c.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+			c.Close()
+			return
+		}
+		io.WriteString(w, "ok")
+	})
+	closed := make(chan struct{}, 2)
+	srv := NewServer(engine.NewEngine(handler),
+		WithConnState(func(conn netpoll.Connection, state http.ConnState) {
+			mu.Lock()
+			states = append(states, state)
+			mu.Unlock()
+			if state == http.StateClosed || state == http.StateHijacked {
+				closed <- struct{}{}
+			}
+		}),
+		WithBaseContext(func(l net.Listener) context.Context {
+			base = l
+			return context.Background()
+		}),
+		WithConnContext(func(ctx context.Context, conn netpoll.Connection) context.Context {
+			return context.WithValue(ctx, ctxKey{}, "per-conn")
+		}),
+	)
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+	<-closed // waitDial's probe.
+
+	mu.Lock()
+	states = nil
+	mu.Unlock()
+	tr := &http.Transport{}
+	client := &http.Client{Transport: tr}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get("http://" + addr + "/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	tr.CloseIdleConnections()
+	<-closed
+
+	mu.Lock()
+	got := fmt.Sprint(states)
+	states = nil
+	mu.Unlock()
+	want := fmt.Sprint([]http.ConnState{http.StateNew, http.StateActive, http.StateIdle, http.StateActive, http.StateIdle, http.StateClosed})
+	if got != want {
+		t.Errorf("states = %s, want %s", got, want)
+	}
+	if base == nil || base.Addr().String() != addr {
+		t.Errorf("BaseContext got listener %v", base)
+	}
+
+	// Hijacked is terminal: closing the connection afterwards reports nothing more.
+	resp, err := (&http.Client{Transport: &http.Transport{}}).Get("http://" + addr + "/hijack")
+	if err != nil {
+		t.Fatalf("hijack request failed: %v", err)
+	}
+	resp.Body.Close()
+	<-closed
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	got = fmt.Sprint(states)
+	mu.Unlock()
+	want = fmt.Sprint([]http.ConnState{http.StateNew, http.StateActive, http.StateHijacked})
+	if got != want {
+		t.Errorf("hijack states = %s, want %s", got, want)
+	}
+}
+
+// TestPreforkChild is a prefork child started by TestPrefork from the test binary.
+func TestNetpollTuning(t *testing.T) {
+	if err := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)), WithPollers(0)).Serve(freeAddr(t)); err == nil {
+		t.Fatal("expected an error for an invalid poller count")
+	}
+
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)),
+		WithPollers(2), WithLoadBalance(netpoll.Random), WithWorkerPool(64), WithBufferSize(16<<10))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	waitDial(t, "tcp", addr)
+
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	cfg := srv.Config()
+	if cfg.Pollers != 2 || cfg.LoadBalance != netpoll.Random || cfg.WorkerPoolSize != 64 || cfg.BufferSize != 16<<10 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	// A second server inherits the running settings and cannot change them.
+	if got := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler))).Config(); got.Pollers != 2 || got.WorkerPoolSize != 64 {
+		t.Errorf("expected inherited settings, got %+v", got)
+	}
+	conflicting := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)), WithPollers(3))
+	if err := conflicting.Serve(freeAddr(t)); !errors.Is(err, errTuningConflict) {
+		t.Errorf("expected errTuningConflict, got %v", err)
+	}
+
+	// Once the first server is gone the settings can change again.
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	next := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)), WithPollers(3))
+	addr = freeAddr(t)
+	go next.Serve(addr)
+	defer next.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+	if got := next.Config().Pollers; got != 3 {
+		t.Errorf("expected 3 pollers, got %d", got)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	srv := NewServer(engine.NewEngine(mux, engine.WithMetrics(m)), WithMetrics(m))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Post("http://"+addr+"/echo", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	time.Sleep(50 * time.Millisecond) // Let the close callback run.
+
+	resp, err = client.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("metrics request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	out := string(body)
+	for _, want := range []string{
+		// The waitDial probe and the echo request are closed; the metrics request is still open.
+		"netpoll_http_connections_accepted_total 3\n",
+		"netpoll_http_connections_active 1\n",
+		`netpoll_http_requests_total{class="2xx"} 1` + "\n",
+		"netpoll_http_request_body_bytes_total 5\n",
+		"netpoll_http_response_body_bytes_total 5\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent log writes.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestLogger(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	srv := NewServer(engine.NewEngine(handler, engine.WithLogger(logger)), WithLogger(logger))
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	rawGet(t, addr, nil)
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	c.Write([]byte("NOT HTTP\r\n\r\n"))
+	time.Sleep(100 * time.Millisecond)
+	c.Close()
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="server listening" network=tcp addr=` + addr,
+		`level=ERROR msg="panic in handler" remote_addr=127.0.0.1:`,
+		`method=GET path=/ panic=boom`,
+		`level=WARN msg="failed to read request" remote_addr=127.0.0.1:`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, "conn_id=") {
+		t.Errorf("expected connection ids in:\n%s", out)
+	}
+}
+
+func TestReadTimeouts(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	e := engine.NewEngine(handler,
+		engine.WithIdleTimeout(200*time.Millisecond),
+		engine.WithReadHeaderTimeout(100*time.Millisecond),
+		engine.WithBodyReadTimeout(300*time.Millisecond),
+		engine.WithMinBodyRate(1000, 100*time.Millisecond),
+	)
+	srv := NewServer(e)
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	// exchange sends raw and returns everything the server writes until it closes the connection.
+	exchange := func(t *testing.T, raw string) string {
+		t.Helper()
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer c.Close()
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		c.Write([]byte(raw))
+		out, err := io.ReadAll(c)
+		if err != nil {
+			t.Fatalf("connection was not closed by the server: %v", err)
+		}
+		return string(out)
+	}
+
+	t.Run("keep-alive then idle", func(t *testing.T) {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer c.Close()
+		br := bufio.NewReader(c)
+		for _, raw := range []string{
+			"GET / HTTP/1.1\r\nHost: x\r\n\r\n",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 2\r\n\r\nhi",
+		} {
+			c.Write([]byte(raw))
+			resp, err := http.ReadResponse(br, nil)
+			if err != nil {
+				t.Fatalf("read response failed: %v", err)
+			}
+			io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected 200, got %d", resp.StatusCode)
+			}
+		}
+		start := time.Now()
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if out, err := io.ReadAll(br); err != nil || len(out) != 0 {
+			t.Errorf("expected a silent close, got %q, %v", out, err)
+		}
+		if d := time.Since(start); d < 150*time.Millisecond {
+			t.Errorf("idle connection closed after %v", d)
+		}
+	})
+	t.Run("slow headers", func(t *testing.T) {
+		out := exchange(t, "GET / HTTP/1.1\r\nHost: x\r\n")
+		if !strings.HasPrefix(out, "HTTP/1.1 408 ") {
+			t.Errorf("expected 408, got %q", out)
+		}
+	})
+	t.Run("slow body", func(t *testing.T) {
+		out := exchange(t, "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 10\r\n\r\nabc")
+		if !strings.HasPrefix(out, "HTTP/1.1 408 ") {
+			t.Errorf("expected 408, got %q", out)
+		}
+	})
+	t.Run("body below minimum rate", func(t *testing.T) {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer c.Close()
+		start := time.Now()
+		c.Write([]byte("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 100000\r\n\r\n"))
+		// Stall after 100 bytes: the rate deadline passes well before the body timeout.
+		c.Write(bytes.Repeat([]byte("x"), 100))
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		out, _ := io.ReadAll(c)
+		if !strings.HasPrefix(string(out), "HTTP/1.1 408 ") {
+			t.Errorf("expected 408, got %q", out)
+		}
+		if d := time.Since(start); d > 290*time.Millisecond {
+			t.Errorf("rate limit took %v, longer than the body timeout", d)
+		}
+	})
+}
+
+func TestPipelining(t *testing.T) {
+	// Earlier requests sleep longer, so concurrent handlers finish in reverse order.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		time.Sleep(time.Duration(5-n) * 10 * time.Millisecond)
+		body, _ := io.ReadAll(r.Body)
+		w.Write(append(body, r.URL.Path...))
+	})
+
+	for _, tc := range []struct {
+		name string
+		opts []engine.Option
+	}{
+		{"sequential", nil},
+		{"concurrent", []engine.Option{engine.WithPipelineConcurrency(4)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := NewServer(engine.NewEngine(handler, tc.opts...))
+			addr := freeAddr(t)
+			go srv.Serve(addr)
+			defer srv.Shutdown(context.Background())
+			waitDial(t, "tcp", addr)
+
+			c, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("dial failed: %v", err)
+			}
+			defer c.Close()
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+			// A request with a body in the middle splits the concurrent batches.
+			var raw strings.Builder
+			for i := 0; i < 5; i++ {
+				if i == 2 {
+					fmt.Fprintf(&raw, "POST /%d HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\n\r\nbody", i)
+					continue
+				}
+				fmt.Fprintf(&raw, "GET /%d HTTP/1.1\r\nHost: x\r\n\r\n", i)
+			}
+			c.Write([]byte(raw.String()))
+
+			br := bufio.NewReader(c)
+			for i := 0; i < 5; i++ {
+				resp, err := http.ReadResponse(br, nil)
+				if err != nil {
+					t.Fatalf("response %d: %v", i, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				want := fmt.Sprintf("/%d", i)
+				if i == 2 {
+					want = "body" + want
+				}
+				if string(body) != want {
+					t.Errorf("response %d: expected %q, got %q", i, want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestExecutionPool(t *testing.T) {
+	started := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		w.Write([]byte("ok"))
+	}), engine.WithExecutionPool(1, 1, 200*time.Millisecond))
+	srv := NewServer(e)
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	type result struct {
+		status     int
+		retryAfter string
+		err        error
+	}
+	get := func(ch chan<- result) {
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			ch <- result{err: err}
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		ch <- result{resp.StatusCode, resp.Header.Get("Retry-After"), nil}
+	}
+
+	// The first request holds the only worker.
+	first := make(chan result, 1)
+	go get(first)
+	<-started
+
+	// The second waits in the queue, so the third finds it full and is shed at once.
+	queued := make(chan result, 1)
+	go get(queued)
+	deadline := time.Now().Add(time.Second)
+	for e.ExecutionStats().Queued != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	shed := make(chan result, 1)
+	get(shed)
+	if r := <-shed; r.err != nil || r.status != http.StatusServiceUnavailable || r.retryAfter != "1" {
+		t.Errorf("full queue: expected 503 with Retry-After 1, got %+v", r)
+	}
+
+	// The queued request gives up after the maximum wait.
+	if r := <-queued; r.err != nil || r.status != http.StatusServiceUnavailable {
+		t.Errorf("expired wait: expected 503, got %+v", r)
+	}
+
+	close(unblock)
+	if r := <-first; r.err != nil || r.status != http.StatusOK {
+		t.Errorf("admitted request: expected 200, got %+v", r)
+	}
+	if st := e.ExecutionStats(); st.Workers != 1 || st.Active != 0 || st.Queued != 0 || st.Shed != 2 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow", "/extended":
+			time.Sleep(200 * time.Millisecond)
+			_, err := w.Write([]byte("late"))
+			if r.URL.Path == "/slow" {
+				lateWrite <- err
+			}
+		case "/stream":
+			w.Write([]byte("part"))
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.Write([]byte("ok"))
+		}
+	})
+	// Per-route override: /extended gets more time than the engine default.
+	extend := func(next engine.HandlerFunc) engine.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c *engine.Context) {
+			if r.URL.Path == "/extended" && !engine.SetRequestTimeout(r, time.Second) {
+				t.Error("SetRequestTimeout failed")
+			}
+			next(w, r, c)
+		}
+	}
+
+	start := func(t *testing.T, opts ...engine.Option) string {
+		e := engine.NewEngine(handler, append(opts, engine.WithRequestTimeout(50*time.Millisecond))...)
+		e.Use(extend)
+		srv := NewServer(e)
+		addr := freeAddr(t)
+		go srv.Serve(addr)
+		t.Cleanup(func() { srv.Shutdown(context.Background()) })
+		waitDial(t, "tcp", addr)
+		return "http://" + addr
+	}
+	client := &http.Client{Transport: &http.Transport{}}
+	defer client.CloseIdleConnections()
+	get := func(t *testing.T, url string) (int, string, error) {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), err
+	}
+
+	t.Run("default response", func(t *testing.T) {
+		base := start(t)
+		code, body, err := get(t, base+"/slow")
+		if err != nil || code != http.StatusServiceUnavailable || !strings.Contains(body, "Timeout") {
+			t.Fatalf("expected the 503 timeout page, got %d %q %v", code, body, err)
+		}
+		if err := <-lateWrite; !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("expected ErrHandlerTimeout for the late write, got %v", err)
+		}
+		// The connection stays usable after a timeout.
+		if code, body, err := get(t, base+"/"); err != nil || code != http.StatusOK || body != "ok" {
+			t.Errorf("expected 200 after the timeout, got %d %q %v", code, body, err)
+		}
+		if code, body, err := get(t, base+"/extended"); err != nil || code != http.StatusOK || body != "late" {
+			t.Errorf("expected the extended route to finish, got %d %q %v", code, body, err)
+		}
+		if _, _, err := get(t, base+"/stream"); err == nil {
+			t.Error("expected a streamed response cut off by the timeout to fail")
+		}
+	})
+
+	t.Run("custom response", func(t *testing.T) {
+		base := start(t, engine.WithTimeoutResponse(http.StatusGatewayTimeout, "too slow"))
+		code, body, err := get(t, base+"/slow")
+		<-lateWrite
+		if err != nil || code != http.StatusGatewayTimeout || body != "too slow" {
+			t.Errorf("expected 504 %q, got %d %q %v", "too slow", code, body, err)
+		}
+	})
+}
+
+func TestPanicHandling(t *testing.T) {
+	var mu sync.Mutex
+	var reported []string
+	m := metrics.New()
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/abort":
+			panic(http.ErrAbortHandler)
+		case "/stream":
+			w.Write([]byte("part"))
+			w.(http.Flusher).Flush()
+		}
+		w.Header().Set("X-Discarded", "1")
+		panic("boom " + r.URL.Path)
+	}), engine.WithMetrics(m), engine.WithPanicHandler(func(w http.ResponseWriter, r *http.Request, recovered any, stack []byte) {
+		mu.Lock()
+		reported = append(reported, fmt.Sprint(recovered))
+		mu.Unlock()
+		if !bytes.Contains(stack, []byte("TestPanicHandling")) {
+			t.Errorf("stack does not show the handler:\n%s", stack)
+		}
+		if r.URL.Path == "/custom" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("custom"))
+		}
+	}))
+	srv := NewServer(e)
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	client := &http.Client{Transport: &http.Transport{}}
+	defer client.CloseIdleConnections()
+	get := func(path string) (*http.Response, string, error) {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp, string(body), err
+	}
+
+	if resp, body, err := get("/custom"); err != nil || resp.StatusCode != http.StatusServiceUnavailable || body != "custom" || resp.Header.Get("X-Discarded") != "" {
+		t.Errorf("custom response: got %v %q %v", resp, body, err)
+	}
+	if resp, body, err := get("/silent"); err != nil || resp.StatusCode != http.StatusInternalServerError || body != "" {
+		t.Errorf("default 500: got %v %q %v", resp, body, err)
+	}
+	if _, _, err := get("/stream"); err == nil {
+		t.Error("expected a streamed response cut off by a panic to fail")
+	}
+	if _, _, err := get("/abort"); err == nil {
+		t.Error("expected ErrAbortHandler to drop the connection")
+	}
+
+	mu.Lock()
+	got := strings.Join(reported, ",")
+	mu.Unlock()
+	if got != "boom /custom,boom /silent,boom /stream" {
+		t.Errorf("unexpected reports %q", got)
+	}
+	var out strings.Builder
+	m.WriteTo(&out)
+	if !strings.Contains(out.String(), "netpoll_http_handler_panics_total 3\n") {
+		t.Errorf("expected 3 panics in the metrics:\n%s", out.String())
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var out syncBuffer
+	logger := accesslog.New(&out, accesslog.WithFormat(accesslog.Common), accesslog.WithSkip(accesslog.SkipPaths("/skip")))
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/chunked":
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("second"))
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.Write([]byte("hello"))
+		}
+	}), engine.WithAccessLog(logger))
+	srv := NewServer(e)
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	// The logged byte count must equal what the client received, framing included.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	counted := &countingReader{r: conn}
+	br := bufio.NewReader(counted)
+	var wire []int64
+	for _, path := range []string{"/fixed", "/chunked", "/missing", "/skip"} {
+		fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: x\r\n\r\n", path)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		wire = append(wire, counted.n-int64(br.Buffered()))
+		counted.n = int64(br.Buffered())
+	}
+	srv.Shutdown(context.Background())
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", out.String())
+	}
+	for i, want := range []string{
+		fmt.Sprintf(`"GET /fixed HTTP/1.1" 200 %d`, wire[0]),
+		fmt.Sprintf(`"GET /chunked HTTP/1.1" 200 %d`, wire[1]),
+		fmt.Sprintf(`"GET /missing HTTP/1.1" 404 %d`, wire[2]),
+	} {
+		if !strings.HasPrefix(lines[i], "127.0.0.1 - - [") || !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %d: expected suffix %q, got %q", i, want, lines[i])
+		}
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestConnLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opt   engine.Option
+		pause time.Duration
+	}{
+		{"requests", engine.WithMaxRequestsPerConn(2), 0},
+		{"age", engine.WithMaxConnAge(100*time.Millisecond, 50*time.Millisecond), 150 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}), tc.opt)
+			srv := NewServer(e)
+			addr := freeAddr(t)
+			go srv.Serve(addr)
+			defer srv.Shutdown(context.Background())
+			waitDial(t, "tcp", addr)
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("dial failed: %v", err)
+			}
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+			for i, wantClose := range []bool{false, true} {
+				if i > 0 {
+					time.Sleep(tc.pause)
+				}
+				fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+				resp, err := http.ReadResponse(br, nil)
+				if err != nil {
+					t.Fatalf("request %d: %v", i, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != "ok" || resp.Close != wantClose {
+					t.Errorf("request %d: expected close=%v, got %q close=%v", i, wantClose, body, resp.Close)
+				}
+			}
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, err := br.ReadByte(); err != io.EOF {
+				t.Errorf("expected the server to close the connection, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExpectContinue(t *testing.T) {
+	start := func(t *testing.T, opts ...engine.Option) net.Conn {
+		e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/read" {
+				body, _ := io.ReadAll(r.Body)
+				w.Write(body)
+				return
+			}
+			w.Write([]byte("skipped"))
+		}), opts...)
+		srv := NewServer(e)
+		addr := freeAddr(t)
+		go srv.Serve(addr)
+		t.Cleanup(func() { srv.Shutdown(context.Background()) })
+		waitDial(t, "tcp", addr)
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+	const upload = "POST %s HTTP/1.1\r\nHost: x\r\nExpect: %s\r\nContent-Length: 4\r\n\r\n"
+	read := func(t *testing.T, br *bufio.Reader) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("reading response: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+	expectEOF := func(t *testing.T, br *bufio.Reader) {
+		t.Helper()
+		if _, err := br.ReadByte(); err != io.EOF {
+			t.Errorf("expected the connection to be closed, got %v", err)
+		}
+	}
+
+	t.Run("read", func(t *testing.T) {
+		conn := start(t)
+		br := bufio.NewReader(conn)
+		fmt.Fprintf(conn, upload, "/read", "100-continue")
+		if resp, _ := read(t, br); resp.StatusCode != http.StatusContinue {
+			t.Fatalf("expected 100 Continue, got %d", resp.StatusCode)
+		}
+		fmt.Fprint(conn, "data")
+		if resp, body := read(t, br); resp.StatusCode != http.StatusOK || body != "data" || resp.Close {
+			t.Errorf("expected the echoed body, got %d %q close=%v", resp.StatusCode, body, resp.Close)
+		}
+		fmt.Fprintf(conn, upload, "/read", "100-Continue")
+		read(t, br)
+	})
+
+	t.Run("unread closes", func(t *testing.T) {
+		conn := start(t)
+		br := bufio.NewReader(conn)
+		fmt.Fprintf(conn, upload, "/skip", "100-continue")
+		if resp, body := read(t, br); resp.StatusCode != http.StatusOK || body != "skipped" || !resp.Close {
+			t.Errorf("expected a final response with close, got %d %q close=%v", resp.StatusCode, body, resp.Close)
+		}
+		expectEOF(t, br)
+	})
+
+	t.Run("unread drains", func(t *testing.T) {
+		conn := start(t, engine.WithExpectContinue(engine.ContinueDrain))
+		br := bufio.NewReader(conn)
+		fmt.Fprintf(conn, upload, "/skip", "100-continue")
+		if resp, body := read(t, br); body != "skipped" || resp.Close {
+			t.Errorf("expected a keep-alive response, got %q close=%v", body, resp.Close)
+		}
+		fmt.Fprint(conn, "data")
+		fmt.Fprintf(conn, upload, "/read", "100-continue")
+		read(t, br)
+		fmt.Fprint(conn, "next")
+		if _, body := read(t, br); body != "next" {
+			t.Errorf("expected the second body, got %q", body)
+		}
+	})
+
+	for _, tc := range []struct {
+		name, expect string
+		opts         []engine.Option
+	}{
+		{"rejected", "100-continue", []engine.Option{engine.WithExpectContinue(engine.ContinueReject)}},
+		{"unknown expectation", "something-else", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := start(t, tc.opts...)
+			br := bufio.NewReader(conn)
+			fmt.Fprintf(conn, upload, "/read", tc.expect)
+			if resp, _ := read(t, br); resp.StatusCode != http.StatusExpectationFailed || !resp.Close {
+				t.Errorf("expected 417 with close, got %d close=%v", resp.StatusCode, resp.Close)
+			}
+			expectEOF(t, br)
+		})
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/read":
+			body, err := io.ReadAll(r.Body)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return
+			}
+			w.Write(body)
+		case "/custom":
+			if _, err := io.ReadAll(r.Body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		default:
+			w.Write([]byte("ignored"))
+		}
+	}), engine.WithMaxHeaderBytes(1024), engine.WithMaxBodyBytes(64), engine.WithMaxDrainBytes(16))
+	srv := NewServer(e)
+	addr := freeAddr(t)
+	go srv.Serve(addr)
+	defer srv.Shutdown(context.Background())
+	waitDial(t, "tcp", addr)
+
+	chunked := "Transfer-Encoding: chunked\r\n\r\n50\r\n" + strings.Repeat("x", 80) + "\r\n0\r\n\r\n"
+	for _, tc := range []struct {
+		name, raw string
+		status    int
+		keepAlive bool
+	}{
+		{"within limits", "POST /read HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\n\r\ndata", http.StatusOK, true},
+		{"headers too large", "GET / HTTP/1.1\r\nHost: x\r\nX-Big: " + strings.Repeat("x", 8<<10) + "\r\n\r\n", http.StatusRequestHeaderFieldsTooLarge, false},
+		{"announced body too large", "POST /read HTTP/1.1\r\nHost: x\r\nContent-Length: 100\r\n\r\n", http.StatusRequestEntityTooLarge, false},
+		{"streamed body too large", "POST /read HTTP/1.1\r\nHost: x\r\n" + chunked, http.StatusRequestEntityTooLarge, false},
+		{"handler status kept", "POST /custom HTTP/1.1\r\nHost: x\r\n" + chunked, http.StatusBadRequest, false},
+		{"small unread body drained", "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 10\r\n\r\n0123456789", http.StatusOK, true},
+		{"large unread body", "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 40\r\n\r\n" + strings.Repeat("x", 40), http.StatusOK, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("dial failed: %v", err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			br := bufio.NewReader(conn)
+			fmt.Fprint(conn, tc.raw)
+			resp, err := http.ReadResponse(br, nil)
+			if err != nil {
+				t.Fatalf("reading response: %v", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			if resp.StatusCode != tc.status || resp.Close == tc.keepAlive {
+				t.Fatalf("expected %d with keep-alive %v, got %d close=%v", tc.status, tc.keepAlive, resp.StatusCode, resp.Close)
+			}
+			if !tc.keepAlive {
+				if _, err := br.ReadByte(); err != io.EOF {
+					t.Errorf("expected the connection to be closed, got %v", err)
+				}
+				return
+			}
+			fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+			if resp, err := http.ReadResponse(br, nil); err != nil || resp.StatusCode != http.StatusOK {
+				t.Errorf("expected the connection to be reused, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPreforkChild(t *testing.T) {
+	if os.Getenv("PREFORK_TEST_ADDR") == "" || !IsPreforkChild() {
+		t.Skip("helper process for TestPrefork")
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crash" {
+			os.Exit(3)
+		}
+		pidHandler(w, r)
+	})
+	srv := NewServer(engine.NewEngine(handler), WithPrefork(2))
+	srv.Serve(os.Getenv("PREFORK_TEST_ADDR"))
+	os.Exit(0)
+}
+
+func TestPrefork(t *testing.T) {
+	addr := freeAddr(t)
+	t.Setenv("PREFORK_TEST_ADDR", addr)
+	srv := NewServer(engine.NewEngine(http.HandlerFunc(pidHandler)),
+		WithPrefork(2), WithPreforkCPUPinning(true), WithUpgradeCommand(os.Args[0], "-test.run=^TestPreforkChild$"))
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(addr) }()
+	waitDial(t, "tcp", addr)
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: time.Second}
+	// pids collects the children answering on addr; SO_REUSEPORT spreads connections between them.
+	pids := func() map[string]bool {
+		seen := make(map[string]bool)
+		for i := 0; i < 100 && len(seen) < 2; i++ {
+			resp, err := client.Get("http://" + addr + "/")
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			seen[string(body)] = true
+		}
+		return seen
+	}
+
+	before := pids()
+	if len(before) != 2 || before[strconv.Itoa(os.Getpid())] {
+		t.Fatalf("expected two child processes, got %v", before)
+	}
+
+	// A crashed child is replaced by a new process.
+	client.Get("http://" + addr + "/crash")
+	time.Sleep(200 * time.Millisecond)
+	after := pids()
+	replaced := false
+	for pid := range after {
+		if !before[pid] {
+			replaced = true
+		}
+	}
+	if len(after) != 2 || !replaced {
+		t.Errorf("expected a restarted child, before %v after %v", before, after)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+	if c, err := net.Dial("tcp", addr); err == nil {
+		c.Close()
+		t.Errorf("expected no process listening after shutdown")
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+)
+
+// ShutdownReport summarizes how connections ended during GracefulShutdown.
+// ShutdownReport는 GracefulShutdown 동안 연결이 어떻게 종료되었는지 요약합니다.
+type ShutdownReport struct {
+	// IdleClosed counts connections closed immediately because no request was in flight.
+	// IdleClosed는 진행 중인 요청이 없어 즉시 닫힌 연결 수입니다.
+	IdleClosed int
+	// Drained counts connections that finished their in-flight work before the deadline.
+	// Drained는 마감 시간 전에 진행 중인 작업을 마친 연결 수입니다.
+	Drained int
+	// ForceClosed lists connections that were still open when the context expired.
+	// ForceClosed는 컨텍스트가 만료될 때까지 열려 있던 연결 목록입니다.
+	ForceClosed []ForcedConn
+}
+
+// ForcedConn describes a connection closed by force at the shutdown deadline.
+// ForcedConn은 종료 마감 시간에 강제로 닫힌 연결을 설명합니다.
+type ForcedConn struct {
+	ID         uint64
+	RemoteAddr string
+	State      http.ConnState
+	Age        time.Duration
+}
+
+// RegisterOnShutdown registers a function to call when shutdown starts.
+// It is meant for hijacked connections, such as WebSockets, that the server can no longer see.
+// RegisterOnShutdown은 종료가 시작될 때 호출할 함수를 등록합니다.
+// 서버가 더 이상 관리하지 않는 WebSocket 같은 하이재킹된 연결을 위한 것입니다.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.mu.Lock()
+	s.onShutdown = append(s.onShutdown, f)
+	s.mu.Unlock()
+}
+
+// trackConn registers a new connection, refusing it once shutdown has started or an admission limit is hit.
+// trackConn은 새 연결을 등록하며, 종료가 시작되었거나 수락 제한에 걸리면 거부합니다.
+func (s *Server) trackConn(c *appcontext.Conn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inShutdown {
+		return http.ErrServerClosed
+	}
+	ip := remoteIP(c.Netpoll())
+	if err := s.admit(ip); err != nil {
+		return err
+	}
+	if s.conns == nil {
+		s.conns = make(map[*appcontext.Conn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	if s.maxConnsPerIP > 0 && ip != "" {
+		if s.connsPerIP == nil {
+			s.connsPerIP = make(map[string]int)
+		}
+		s.connsPerIP[ip]++
+	}
+	return nil
+}
+
+// untrackConn forgets a closed connection and releases its admission slot.
+// untrackConn은 닫힌 연결을 추적 대상에서 제외하고 수락 슬롯을 반환합니다.
+func (s *Server) untrackConn(c *appcontext.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.conns[c]; !ok {
+		return
+	}
+	delete(s.conns, c)
+	if ip := remoteIP(c.Netpoll()); s.maxConnsPerIP > 0 && ip != "" {
+		if s.connsPerIP[ip]--; s.connsPerIP[ip] <= 0 {
+			delete(s.connsPerIP, ip)
+		}
+	}
+}
+
+// GracefulShutdown stops accepting connections and drains the open ones, like http.Server.Shutdown.
+// Idle keep-alive connections are closed immediately, active requests get "Connection: close" on their
+// final response, and hijacked or streaming handlers are notified through appcontext.Conn.DrainNotify
+// and RegisterOnShutdown. Connections still open when ctx expires are closed and listed in the report.
+// GracefulShutdown은 http.Server.Shutdown처럼 새 연결 수락을 멈추고 열린 연결을 드레이닝합니다.
+// 유휴 keep-alive 연결은 즉시 닫히고, 활성 요청은 마지막 응답에 "Connection: close"를 받으며,
+// 하이재킹되었거나 스트리밍 중인 핸들러는 appcontext.Conn.DrainNotify와 RegisterOnShutdown으로 통지받습니다.
+// ctx가 만료될 때까지 열려 있는 연결은 닫히고 보고서에 기록됩니다.
+func (s *Server) GracefulShutdown(ctx context.Context) (ShutdownReport, error) {
+	var report ShutdownReport
+
+	s.mu.Lock()
+	s.inShutdown = true
+	eventLoop := s.eventLoop
+	hooks := s.onShutdown
+	sv := s.supervisor
+	s.mu.Unlock()
+
+	// A prefork parent has no connections of its own; each child drains its own.
+	// prefork 부모는 자체 연결이 없으며, 각 자식이 자신의 연결을 드레이닝합니다.
+	if sv != nil {
+		return report, sv.shutdown(ctx)
+	}
+	// Every connection is closed on return, so the netpoll settings are free again.
+	// 반환 시점에는 모든 연결이 닫혀 있으므로 netpoll 설정을 다시 바꿀 수 있습니다.
+	defer s.releaseTuning()
+
+	for _, f := range hooks {
+		go f()
+	}
+
+	// netpoll closes the listener right away and then waits on its own view of the connections.
+	// netpoll은 즉시 리스너를 닫은 뒤 자체적으로 연결을 기다립니다.
+	loopDone := make(chan error, 1)
+	if eventLoop != nil {
+		go func() { loopDone <- eventLoop.Shutdown(ctx) }()
+	} else {
+		loopDone <- nil
+	}
+
+	seen := make(map[*appcontext.Conn]struct{})
+	for {
+		remaining := s.drainConns(seen, &report)
+		if remaining == 0 {
+			break
+		}
+
+		// Poll more slowly when many connections are still busy, as netpoll does.
+		// netpoll과 같이 바쁜 연결이 많을수록 더 느리게 확인합니다.
+		wait := time.Duration(remaining) * time.Millisecond
+		wait = min(max(wait, 50*time.Millisecond), time.Second)
+		select {
+		case <-ctx.Done():
+			s.forceClose(seen, &report)
+			report.Drained = len(seen) - report.IdleClosed - len(report.ForceClosed)
+			return report, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	report.Drained = len(seen) - report.IdleClosed
+	select {
+	case err := <-loopDone:
+		return report, err
+	case <-ctx.Done():
+		return report, ctx.Err()
+	}
+}
+
+// drainConns asks every tracked connection to drain, closes the idle ones and returns how many remain.
+// drainConns는 추적 중인 모든 연결에 드레이닝을 요청하고, 유휴 연결을 닫은 뒤 남은 연결 수를 반환합니다.
+func (s *Server) drainConns(seen map[*appcontext.Conn]struct{}, report *ShutdownReport) int {
+	var idle []*appcontext.Conn
+	s.mu.Lock()
+	for c := range s.conns {
+		if _, ok := seen[c]; !ok {
+			seen[c] = struct{}{}
+			c.StartDrain()
+		}
+		if c.CompareAndSwapState(http.StateIdle, http.StateClosed) || c.CompareAndSwapState(http.StateNew, http.StateClosed) {
+			idle = append(idle, c)
+		}
+	}
+	remaining := len(s.conns) - len(idle)
+	s.mu.Unlock()
+
+	// Closing runs the close callback, which untracks the connection under s.mu.
+	// 연결을 닫으면 s.mu를 잡고 추적을 해제하는 종료 콜백이 실행됩니다.
+	for _, c := range idle {
+		_ = c.Close()
+	}
+	report.IdleClosed += len(idle)
+	return remaining
+}
+
+// forceClose closes every connection still open at the deadline and records it in the report.
+// forceClose는 마감 시간까지 열려 있는 모든 연결을 닫고 보고서에 기록합니다.
+func (s *Server) forceClose(seen map[*appcontext.Conn]struct{}, report *ShutdownReport) {
+	s.mu.Lock()
+	conns := make([]*appcontext.Conn, 0, len(s.conns))
+	for c := range s.conns {
+		if c.State() == http.StateClosed {
+			continue // Already closing. // 이미 닫히는 중입니다.
+		}
+		seen[c] = struct{}{}
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range conns {
+		fc := ForcedConn{
+			ID:    c.ID(),
+			State: c.State(),
+			Age:   now.Sub(c.CreatedAt()),
+		}
+		if addr := c.Netpoll().RemoteAddr(); addr != nil {
+			fc.RemoteAddr = addr.String()
+		}
+		report.ForceClosed = append(report.ForceClosed, fc)
+		_ = c.Close()
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"github.com/cloudwego/netpoll"
+)
+
+var errTLSConfigMissing = errors.New("server: TLS config requires at least one certificate or GetCertificate")
+
+// WithTLSConfig enables TLS termination on every accepted connection.
+// The config is cloned, so later modifications by the caller have no effect.
+// WithTLSConfig는 수락된 모든 연결에서 TLS 종료를 활성화합니다.
+// 설정은 복제되므로 이후 호출자가 수정해도 영향을 주지 않습니다.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *Server) {
+		if cfg != nil {
+			s.tlsConfig = cfg.Clone()
+		}
+	}
+}
+
+// ServeTLS loads the certificate pair and serves HTTPS on addr.
+// Certificates already present in the TLS config take precedence when both files are empty.
+// ServeTLS는 인증서 쌍을 로드하고 addr에서 HTTPS를 제공합니다.
+// 두 파일이 모두 비어 있으면 TLS 설정에 이미 있는 인증서가 사용됩니다.
+func (s *Server) ServeTLS(addr, certFile, keyFile string) error {
+	cfg := s.tlsConfig
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return errTLSConfigMissing
+	}
+
+	s.tlsConfig = cfg
+	return s.Serve(addr)
+}
+
+type ctxTLSConnKeyStruct struct{}
+
+var ctxTLSConnKey = ctxTLSConnKeyStruct{}
+
+// tlsConn wraps a netpoll.Connection with a server-side TLS session.
+// Reads and writes go through crypto/tls, while Writer() buffers plaintext the same way
+// netpoll.Writer does so that adaptor.ResponseWriter works unchanged.
+// tlsConn은 netpoll.Connection을 서버 측 TLS 세션으로 래핑합니다.
+// 읽기와 쓰기는 crypto/tls를 거치며, Writer()는 netpoll.Writer와 같은 방식으로 평문을 버퍼링하여
+// adaptor.ResponseWriter가 변경 없이 동작하도록 합니다.
+type tlsConn struct {
+	netpoll.Connection
+	tc     *tls.Conn
+	writer netpoll.Writer
+}
+
+func newTLSConn(conn netpoll.Connection, cfg *tls.Config) *tlsConn {
+	tc := tls.Server(conn, cfg)
+	return &tlsConn{
+		Connection: conn,
+		tc:         tc,
+		writer:     netpoll.NewWriter(tc),
+	}
+}
+
+// Handshake runs the TLS handshake if it has not completed yet.
+// Handshake는 TLS 핸드셰이크가 아직 완료되지 않았다면 수행합니다.
+func (c *tlsConn) Handshake(ctx context.Context) error {
+	return c.tc.HandshakeContext(ctx)
+}
+
+func (c *tlsConn) Read(p []byte) (int, error) {
+	return c.tc.Read(p)
+}
+
+func (c *tlsConn) Write(p []byte) (int, error) {
+	return c.tc.Write(p)
+}
+
+func (c *tlsConn) Writer() netpoll.Writer {
+	return c.writer
+}
+
+func (c *tlsConn) Close() error {
+	return c.tc.Close()
+}
+
+// ConnectionState exposes the negotiated TLS state; adaptor.GetRequest uses it to populate req.TLS.
+// ConnectionState는 협상된 TLS 상태를 노출하며, adaptor.GetRequest가 req.TLS를 채우는 데 사용합니다.
+func (c *tlsConn) ConnectionState() tls.ConnectionState {
+	return c.tc.ConnectionState()
+}
+
+// NetConn returns the underlying netpoll connection.
+// NetConn은 기반 netpoll 연결을 반환합니다.
+func (c *tlsConn) NetConn() net.Conn {
+	return c.Connection
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/cloudwego/netpoll"
+)
+
+var errTuningConflict = errors.New("server: netpoll settings conflict with a running server")
+
+// netpollTuning holds the process-wide netpoll settings. Zero values, and a negative load balance,
+// mean "unset" in a server's own settings and "netpoll's default" in the global ones.
+// netpollTuning은 프로세스 전역 netpoll 설정을 담습니다. 서버 자체 설정에서 0 값과 음수 부하 분산은
+// "설정 안 함"을, 전역 설정에서는 "netpoll 기본값"을 의미합니다.
+type netpollTuning struct {
+	pollers     int
+	loadBalance netpoll.LoadBalance
+	workers     int // 0 is netpoll's default pool, which is unbounded. // 0은 제한이 없는 netpoll 기본 풀입니다.
+	bufferSize  int
+}
+
+// merge returns t with the values set in o.
+// merge는 o에 설정된 값으로 덮어쓴 t를 반환합니다.
+func (t netpollTuning) merge(o netpollTuning) netpollTuning {
+	if o.pollers > 0 {
+		t.pollers = o.pollers
+	}
+	if o.loadBalance >= 0 {
+		t.loadBalance = o.loadBalance
+	}
+	if o.workers > 0 {
+		t.workers = o.workers
+	}
+	if o.bufferSize > 0 {
+		t.bufferSize = o.bufferSize
+	}
+	return t
+}
+
+// netpollGlobal tracks what has been applied to netpoll and how many event loops rely on it.
+// Settings may only change while no event loop is running: netpoll closes pollers that a shrinking
+// poller count leaves behind, along with every connection on them.
+// netpollGlobal은 netpoll에 적용된 설정과 이를 사용하는 이벤트 루프 수를 추적합니다.
+// 설정은 실행 중인 이벤트 루프가 없을 때만 바꿀 수 있습니다. 폴러 수를 줄이면 netpoll이 남는 폴러와
+// 그 위의 모든 연결을 닫기 때문입니다.
+var netpollGlobal = struct {
+	sync.Mutex
+	cur   netpollTuning
+	users int
+}{
+	cur: netpollTuning{
+		pollers:     runtime.GOMAXPROCS(0)/20 + 1, // netpoll's default. // netpoll의 기본값
+		loadBalance: netpoll.RoundRobin,
+		bufferSize:  8 << 10,
+	},
+}
+
+// WithPollers sets the number of netpoll pollers. netpoll's default is GOMAXPROCS/20+1.
+// Like the other netpoll settings this is process-wide; see Config.
+// WithPollers는 netpoll 폴러 수를 설정합니다. netpoll의 기본값은 GOMAXPROCS/20+1입니다.
+// 다른 netpoll 설정과 마찬가지로 프로세스 전역이며, Config를 참고하세요.
+func WithPollers(n int) Option {
+	return func(s *Server) {
+		if n < 1 {
+			s.tuningErr = errors.Join(s.tuningErr, fmt.Errorf("server: invalid poller count %d", n))
+			return
+		}
+		s.tuning.pollers = n
+	}
+}
+
+// WithLoadBalance sets how netpoll spreads new connections over its pollers. The default is netpoll.RoundRobin.
+// WithLoadBalance는 netpoll이 새 연결을 폴러에 분산하는 방식을 설정합니다. 기본값은 netpoll.RoundRobin입니다.
+func WithLoadBalance(lb netpoll.LoadBalance) Option {
+	return func(s *Server) {
+		if lb != netpoll.RoundRobin && lb != netpoll.Random {
+			s.tuningErr = errors.Join(s.tuningErr, fmt.Errorf("server: unknown load balance %d", lb))
+			return
+		}
+		s.tuning.loadBalance = lb
+	}
+}
+
+// WithWorkerPool caps the goroutines running OnRequest at size. By default netpoll's pool is unbounded.
+// HTTP/2 and hijacked connections hold a worker for as long as they are open, so leave room for them.
+// WithWorkerPool은 OnRequest를 실행하는 고루틴 수를 size로 제한합니다. 기본적으로 netpoll의 풀은 무제한입니다.
+// HTTP/2와 하이재킹된 연결은 열려 있는 동안 워커를 점유하므로 여유를 두어야 합니다.
+func WithWorkerPool(size int) Option {
+	return func(s *Server) {
+		if size < 1 {
+			s.tuningErr = errors.Join(s.tuningErr, fmt.Errorf("server: invalid worker pool size %d", size))
+			return
+		}
+		s.tuning.workers = size
+	}
+}
+
+// WithBufferSize sets the initial size in bytes of each connection's netpoll buffer. The default is 8 KiB.
+// WithBufferSize는 각 연결의 netpoll 버퍼 초기 크기를 바이트 단위로 설정합니다. 기본값은 8KiB입니다.
+func WithBufferSize(n int) Option {
+	return func(s *Server) {
+		if n < 1 {
+			s.tuningErr = errors.Join(s.tuningErr, fmt.Errorf("server: invalid buffer size %d", n))
+			return
+		}
+		s.tuning.bufferSize = n
+	}
+}
+
+// Config is a snapshot of a server's effective configuration.
+// Config는 서버의 실제 적용 설정 스냅샷입니다.
+type Config struct {
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+	KeepAliveTimeout time.Duration
+
+	// The netpoll settings are shared by every server in the process. Unset values report what
+	// netpoll currently uses, which another server may have configured.
+	// netpoll 설정은 프로세스의 모든 서버가 공유합니다. 설정하지 않은 값은 다른 서버가 설정했을 수도 있는
+	// 현재 netpoll의 값을 보고합니다.
+	Pollers        int
+	LoadBalance    netpoll.LoadBalance
+	WorkerPoolSize int // 0 means unbounded. // 0은 무제한을 의미합니다.
+	BufferSize     int
+
+	MaxConns      int
+	MaxConnsPerIP int
+	Prefork       int
+	TLS           bool
+	ProxyProtocol bool
+}
+
+// Config returns the server's effective configuration.
+// Config는 서버의 실제 적용 설정을 반환합니다.
+func (s *Server) Config() Config {
+	netpollGlobal.Lock()
+	t := netpollGlobal.cur.merge(s.tuning)
+	netpollGlobal.Unlock()
+
+	return Config{
+		ReadTimeout:      s.readTimeout,
+		WriteTimeout:     s.writeTimeout,
+		KeepAliveTimeout: s.keepAliveTimeout,
+		Pollers:          t.pollers,
+		LoadBalance:      t.loadBalance,
+		WorkerPoolSize:   t.workers,
+		BufferSize:       t.bufferSize,
+		MaxConns:         s.maxConns,
+		MaxConnsPerIP:    s.maxConnsPerIP,
+		Prefork:          s.prefork,
+		TLS:              s.tlsConfig != nil,
+		ProxyProtocol:    s.proxyProtocol,
+	}
+}
+
+// applyTuning applies the server's netpoll settings before its event loop is created, and fails if
+// they differ from the ones a running server depends on. Each successful call is undone by releaseTuning.
+// applyTuning은 이벤트 루프 생성 전에 서버의 netpoll 설정을 적용하며, 실행 중인 서버가 사용하는 설정과
+// 다르면 실패합니다. 성공한 호출은 각각 releaseTuning으로 되돌립니다.
+func (s *Server) applyTuning() error {
+	if s.tuningErr != nil {
+		return s.tuningErr
+	}
+
+	netpollGlobal.Lock()
+	defer netpollGlobal.Unlock()
+	cur := netpollGlobal.cur
+	want := cur.merge(s.tuning)
+	if want != cur {
+		if netpollGlobal.users > 0 {
+			return fmt.Errorf("%w: want %+v, running with %+v", errTuningConflict, want, cur)
+		}
+		cfg := netpoll.Config{
+			PollerNum:   want.pollers,
+			BufferSize:  want.bufferSize,
+			LoadBalance: want.loadBalance,
+		}
+		if want.workers != cur.workers {
+			cfg.Runner = gopool.NewPool("http-over-netpoll", int32(want.workers), gopool.NewConfig()).CtxGo
+		}
+		if err := netpoll.Configure(cfg); err != nil {
+			return err
+		}
+		netpollGlobal.cur = want
+	}
+	netpollGlobal.users++
+
+	s.mu.Lock()
+	s.tuningHeld++
+	s.mu.Unlock()
+	return nil
+}
+
+// releaseTuning lets other servers change the netpoll settings once this one no longer uses them.
+// releaseTuning은 이 서버가 더 이상 사용하지 않을 때 다른 서버가 netpoll 설정을 바꿀 수 있게 합니다.
+func (s *Server) releaseTuning() {
+	s.mu.Lock()
+	n := s.tuningHeld
+	s.tuningHeld = 0
+	s.mu.Unlock()
+
+	netpollGlobal.Lock()
+	netpollGlobal.users -= n
+	netpollGlobal.Unlock()
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+var (
+	errNotServing      = errors.New("server: not serving")
+	errUpgradeNotReady = errors.New("server: upgraded process exited before becoming ready")
+)
+
+// WithUpgradeCommand sets the binary and arguments started by Upgrade and by prefork.
+// By default the running executable is re-executed with the current arguments.
+// WithUpgradeCommand는 Upgrade와 prefork가 실행할 바이너리와 인자를 설정합니다.
+// 기본값은 현재 인자로 실행 중인 바이너리를 다시 실행하는 것입니다.
+func WithUpgradeCommand(path string, args ...string) Option {
+	return func(s *Server) {
+		s.upgradePath = path
+		s.upgradeArgs = args
+	}
+}
+
+// Upgrade hands the listening socket to a new process without dropping connections.
+// The new process receives it through LISTEN_FDS, picks it up with ServeInherited and calls Ready.
+// Once it is ready this server drains through Shutdown; if it exits first, or ctx expires before
+// it is ready, the new process is abandoned and this server keeps serving.
+// Upgrade는 연결을 끊지 않고 수신 소켓을 새 프로세스에 넘깁니다.
+// 새 프로세스는 LISTEN_FDS로 소켓을 받아 ServeInherited로 사용하고 Ready를 호출합니다.
+// 준비가 완료되면 이 서버는 Shutdown으로 드레이닝하며, 새 프로세스가 먼저 종료되거나 준비 전에 ctx가
+// 만료되면 새 프로세스를 포기하고 이 서버가 계속 요청을 처리합니다.
+func (s *Server) Upgrade(ctx context.Context) error {
+	s.mu.Lock()
+	l, name := s.listener, s.listenerName
+	s.mu.Unlock()
+	if l == nil {
+		return errNotServing
+	}
+	if name == "" {
+		name = "unknown"
+	}
+
+	lf, err := listenerFile(l)
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	path, args, err := s.childCommand()
+	if err != nil {
+		readyW.Close()
+		return err
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// ExtraFiles start at fd 3: the listener first, then the readiness pipe.
+	// ExtraFiles는 fd 3부터 시작합니다: 리스너가 먼저, 그다음 준비 알림 파이프입니다.
+	cmd.ExtraFiles = []*os.File{lf, readyW}
+	cmd.Env = append(upgradeEnv(os.Environ()),
+		"LISTEN_FDS=1",
+		"LISTEN_FDNAMES="+name,
+		envReadyFD+"="+strconv.Itoa(listenFdsStart+1),
+	)
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return err
+	}
+	go cmd.Wait() // nolint:errcheck // Reaps the child if it exits early. // 자식이 일찍 종료되면 회수합니다.
+
+	ready := make(chan error, 1)
+	go func() {
+		// EOF means every copy of the write end is gone, i.e. the child exited.
+		// EOF는 쓰기 측의 모든 복사본이 사라졌다는 뜻, 즉 자식이 종료되었음을 의미합니다.
+		_, err := readyR.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			return errUpgradeNotReady
+		}
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("server: waiting for upgraded process: %w", ctx.Err())
+	}
+
+	// The new process owns the socket file now. // 이제 새 프로세스가 소켓 파일을 소유합니다.
+	if ul, ok := l.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+	return s.Shutdown(ctx)
+}
+
+// childCommand returns the binary and arguments for a new process of this server.
+// childCommand는 이 서버의 새 프로세스를 위한 바이너리와 인자를 반환합니다.
+func (s *Server) childCommand() (string, []string, error) {
+	if s.upgradePath != "" {
+		return s.upgradePath, s.upgradeArgs, nil
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return "", nil, err
+	}
+	return path, os.Args[1:], nil
+}
+
+// listenerFile returns a duplicate of the listener's file descriptor.
+// listenerFile은 리스너 파일 디스크립터의 복제본을 반환합니다.
+func listenerFile(l net.Listener) (*os.File, error) {
+	switch ln := l.(type) {
+	case *net.TCPListener:
+		return ln.File()
+	case *net.UnixListener:
+		return ln.File()
+	default:
+		return nil, fmt.Errorf("server: cannot pass %T to another process", l)
+	}
+}
+
+// upgradeEnv drops the activation variables this process received so they are not mistaken
+// for the ones describing the handed-over socket.
+// upgradeEnv는 이 프로세스가 받은 활성화 변수를 제거하여 넘겨주는 소켓을 설명하는 변수와 혼동되지 않게 합니다.
+func upgradeEnv(env []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		switch key {
+		case "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", envReadyFD:
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
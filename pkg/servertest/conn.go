@@ -0,0 +1,167 @@
+package servertest
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// Conn is an in-memory netpoll.Connection for unit tests. Reads consume the input given to NewConn
+// and writes, through Write or Writer, are collected for Bytes and String.
+// Conn은 단위 테스트를 위한 메모리 내 netpoll.Connection입니다. 읽기는 NewConn에 전달한 입력을 소비하고,
+// Write나 Writer를 통한 쓰기는 Bytes와 String으로 확인할 수 있도록 모입니다.
+type Conn struct {
+	reader netpoll.Reader
+	writer netpoll.Writer
+
+	mu        sync.Mutex
+	out       bytes.Buffer
+	closed    bool
+	callbacks []netpoll.CloseCallback
+	local     net.Addr
+	remote    net.Addr
+}
+
+// NewConn returns a connection whose peer sends input and then stops writing.
+// NewConn은 피어가 input을 보낸 뒤 더 이상 쓰지 않는 연결을 반환합니다.
+func NewConn(input []byte) *Conn {
+	c := &Conn{
+		local:  &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 80},
+		remote: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 52000},
+	}
+	c.reader = netpoll.NewReader(bytes.NewReader(input))
+	c.writer = netpoll.NewWriter(connWriter{c})
+	return c
+}
+
+// connWriter collects flushed output. // connWriter는 플러시된 출력을 모읍니다.
+type connWriter struct{ c *Conn }
+
+func (w connWriter) Write(p []byte) (int, error) {
+	w.c.mu.Lock()
+	defer w.c.mu.Unlock()
+	if w.c.closed {
+		return 0, net.ErrClosed
+	}
+	return w.c.out.Write(p)
+}
+
+// Bytes returns a copy of everything written and flushed so far.
+// Bytes는 지금까지 쓰고 플러시된 모든 데이터의 복사본을 반환합니다.
+func (c *Conn) Bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return bytes.Clone(c.out.Bytes())
+}
+
+// String returns everything written and flushed so far.
+// String은 지금까지 쓰고 플러시된 모든 데이터를 반환합니다.
+func (c *Conn) String() string {
+	return string(c.Bytes())
+}
+
+// SetRemoteAddr changes the address reported by RemoteAddr. // SetRemoteAddr은 RemoteAddr이 보고하는 주소를 바꿉니다.
+func (c *Conn) SetRemoteAddr(addr net.Addr) {
+	c.mu.Lock()
+	c.remote = addr
+	c.mu.Unlock()
+}
+
+// SetLocalAddr changes the address reported by LocalAddr. // SetLocalAddr은 LocalAddr이 보고하는 주소를 바꿉니다.
+func (c *Conn) SetLocalAddr(addr net.Addr) {
+	c.mu.Lock()
+	c.local = addr
+	c.mu.Unlock()
+}
+
+// Read reads from the same buffer as Reader, returning io.EOF once the input is consumed.
+// Read는 Reader와 같은 버퍼에서 읽으며, 입력을 모두 소비하면 io.EOF를 반환합니다.
+func (c *Conn) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if c.reader.Len() == 0 {
+		if _, err := c.reader.Peek(1); err != nil {
+			return 0, io.EOF
+		}
+	}
+	b, err := c.reader.Next(min(len(p), c.reader.Len()))
+	if err != nil {
+		return 0, err
+	}
+	return copy(p, b), nil
+}
+
+// Write records p as written to the peer. // Write는 p를 피어에 쓴 것으로 기록합니다.
+func (c *Conn) Write(p []byte) (int, error) {
+	return connWriter{c}.Write(p)
+}
+
+// Close marks the connection closed and runs the close callbacks once.
+// Close는 연결을 닫힌 것으로 표시하고 종료 콜백을 한 번 실행합니다.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	callbacks := c.callbacks
+	c.callbacks = nil
+	c.mu.Unlock()
+
+	for _, cb := range callbacks {
+		_ = cb(c)
+	}
+	return nil
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.local
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remote
+}
+
+func (c *Conn) Reader() netpoll.Reader { return c.reader }
+
+func (c *Conn) Writer() netpoll.Writer { return c.writer }
+
+func (c *Conn) IsActive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.closed
+}
+
+// Deadlines and timeouts are accepted and ignored: reads never block.
+// 데드라인과 타임아웃은 받아들이되 무시합니다. 읽기는 절대 블록되지 않습니다.
+
+func (c *Conn) SetDeadline(time.Time) error         { return nil }
+func (c *Conn) SetReadDeadline(time.Time) error     { return nil }
+func (c *Conn) SetWriteDeadline(time.Time) error    { return nil }
+func (c *Conn) SetReadTimeout(time.Duration) error  { return nil }
+func (c *Conn) SetWriteTimeout(time.Duration) error { return nil }
+func (c *Conn) SetIdleTimeout(time.Duration) error  { return nil }
+
+func (c *Conn) SetOnRequest(netpoll.OnRequest) error { return nil }
+
+func (c *Conn) AddCloseCallback(callback netpoll.CloseCallback) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.callbacks = append(c.callbacks, callback)
+	return nil
+}
+
+var _ netpoll.Connection = (*Conn)(nil)
@@ -0,0 +1,72 @@
+// Package servertest provides utilities for testing handlers on the netpoll engine, like net/http/httptest.
+// servertest 패키지는 net/http/httptest처럼 netpoll 엔진 위에서 핸들러를 테스트하기 위한 도구를 제공합니다.
+package servertest
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/server"
+)
+
+// Server is a netpoll HTTP server listening on a loopback port, for use in end-to-end tests.
+// Server는 종단 간 테스트를 위해 루프백 포트에서 수신 대기하는 netpoll HTTP 서버입니다.
+type Server struct {
+	// URL is the base URL of the form http://ipaddr:port with no trailing slash.
+	// URL은 끝에 슬래시가 없는 http://ipaddr:port 형식의 기본 URL입니다.
+	URL string
+	// Listener is the bound listener; Listener.Addr reports the real address.
+	// Listener는 바인딩된 리스너이며, Listener.Addr이 실제 주소를 보고합니다.
+	Listener net.Listener
+	// Server is the underlying server, for inspecting it or calling GracefulShutdown.
+	// Server는 내부 서버로, 상태를 확인하거나 GracefulShutdown을 호출할 때 사용합니다.
+	Server *server.Server
+
+	client *http.Client
+	done   chan error
+}
+
+// NewServer starts a server for handler on 127.0.0.1:0 and returns once it is accepting connections.
+// The caller should call Close when finished.
+// NewServer는 127.0.0.1:0에서 handler를 위한 서버를 시작하고 연결을 수락할 수 있게 되면 반환합니다.
+// 사용이 끝나면 호출자가 Close를 호출해야 합니다.
+func NewServer(handler http.Handler, opts ...server.Option) *Server {
+	return NewEngineServer(engine.NewEngine(handler), opts...)
+}
+
+// NewEngineServer is like NewServer but serves a configured Engine, for testing engine options such as HTTP/2.
+// NewEngineServer는 NewServer와 같지만 설정된 Engine을 사용하므로 HTTP/2 같은 엔진 옵션을 테스트할 수 있습니다.
+func NewEngineServer(e *engine.Engine, opts ...server.Option) *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("servertest: failed to listen on a port: " + err.Error())
+	}
+
+	s := &Server{
+		URL:      "http://" + l.Addr().String(),
+		Listener: l,
+		Server:   server.NewServer(e, opts...),
+		client:   &http.Client{Transport: &http.Transport{}},
+		done:     make(chan error, 1),
+	}
+	// The socket is already listening, so connections queue until the event loop picks them up.
+	// 소켓은 이미 수신 대기 중이므로 이벤트 루프가 가져갈 때까지 연결은 대기열에 쌓입니다.
+	go func() { s.done <- s.Server.ServeListener(l) }()
+	return s
+}
+
+// Client returns an HTTP client for the server. Its idle connections are closed by Close.
+// Client는 서버용 HTTP 클라이언트를 반환합니다. 유휴 연결은 Close에서 닫힙니다.
+func (s *Server) Client() *http.Client {
+	return s.client
+}
+
+// Close shuts the server down and blocks until all outstanding requests have completed.
+// Close는 서버를 종료하고 진행 중인 모든 요청이 끝날 때까지 대기합니다.
+func (s *Server) Close() {
+	s.client.CloseIdleConnections()
+	_ = s.Server.Shutdown(context.Background())
+	<-s.done
+}
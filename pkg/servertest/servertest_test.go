@@ -0,0 +1,80 @@
+package servertest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/adaptor"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/appcontext"
+	"github.com/DevNewbie1826/http-over-netpoll/pkg/engine"
+)
+
+func TestServer(t *testing.T) {
+	ts := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	defer ts.Close()
+
+	if ts.URL != "http://"+ts.Listener.Addr().String() || strings.HasSuffix(ts.URL, ":0") {
+		t.Fatalf("unexpected URL %q", ts.URL)
+	}
+	resp, err := ts.Client().Get(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(string(body), "127.0.0.1:") {
+		t.Errorf("expected a loopback RemoteAddr, got %q", body)
+	}
+}
+
+func TestConn_ResponseWriter(t *testing.T) {
+	conn := NewConn(nil)
+	ctx := appcontext.NewRequestContext(conn, context.Background())
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	rw := adaptor.NewResponseWriter(ctx, req)
+	rw.Header().Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	rw.WriteHeader(http.StatusAccepted)
+	rw.Write([]byte("hello"))
+	if err := rw.EndResponse(); err != nil {
+		t.Fatalf("EndResponse failed: %v", err)
+	}
+
+	// Header order follows map iteration, so check the fixed parts and the total size.
+	const (
+		head    = "HTTP/1.1 202 Accepted\r\nTransfer-Encoding: chunked\r\n"
+		date    = "Date: Mon, 02 Jan 2006 15:04:05 GMT\r\n"
+		ctype   = "Content-Type: text/plain; charset=utf-8\r\n"
+		trailer = "\r\n5\r\nhello\r\n0\r\n\r\n"
+	)
+	out := conn.String()
+	if !strings.HasPrefix(out, head) || !strings.HasSuffix(out, trailer) ||
+		!strings.Contains(out, date) || !strings.Contains(out, ctype) ||
+		len(out) != len(head)+len(date)+len(ctype)+len(trailer) {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestConn_Engine(t *testing.T) {
+	conn := NewConn([]byte("GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	e := engine.NewEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	_ = e.ServeConn(context.Background(), conn)
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(conn.Bytes())), nil)
+	if err != nil {
+		t.Fatalf("invalid response %q: %v", conn.String(), err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "/a" {
+		t.Errorf("got %d %q", resp.StatusCode, body)
+	}
+}